@@ -11,8 +11,10 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/go-chi/chi/v5"
 	"google.golang.org/grpc"
@@ -20,10 +22,16 @@ import (
 
 	"openaction/internal/api"
 	"openaction/internal/auth"
+	"openaction/internal/autoscale"
 	"openaction/internal/blob"
 	"openaction/internal/config"
 	"openaction/internal/db"
+	"openaction/internal/logstream"
+	"openaction/internal/pki"
+	"openaction/internal/poll"
 	"openaction/internal/pool"
+	"openaction/internal/schedule"
+	"openaction/internal/scheduler"
 	"openaction/internal/secret"
 	"openaction/internal/seed"
 	"openaction/internal/ui"
@@ -64,10 +72,34 @@ func main() {
 	blobStore := blob.New(cfg.DataDir)
 	secretKey := secret.DeriveKey(cfg.SecretKey)
 
+	var authority *pki.Authority
+	if cfg.PKIEnabled {
+		authority, err = pki.Load(ctx, database, filepath.Join(cfg.DataDir, "pki"))
+		if err != nil {
+			log.Fatalf("pki error: %v", err)
+		}
+		authority.CertTTL = cfg.PKICertTTL
+		authority.Hosts = cfg.PKIHosts
+	}
+
+	logBroker := logstream.NewBroker()
+	jobScheduler := &scheduler.Scheduler{
+		DB:            database,
+		Blob:          blobStore,
+		Broker:        logBroker,
+		LeaseTTL:      cfg.JobLeaseTTL,
+		RunnerTimeout: cfg.RunnerTTL,
+		Aging:         cfg.QueueAging,
+		MinFreeDiskMB: cfg.MinFreeDisk,
+		SecretKey:     secretKey,
+	}
 	apiServer := &api.Server{
 		DB:         database,
 		Auth:       authService,
 		Blob:       blobStore,
+		Logs:       logBroker,
+		Scheduler:  jobScheduler,
+		PKI:        authority,
 		DataDir:    cfg.DataDir,
 		SecureOnly: cfg.TLSCertPath != "" && cfg.TLSKeyPath != "",
 		SecretKey:  secretKey,
@@ -80,7 +112,23 @@ func main() {
 		log.Fatalf("sample seed error: %v", err)
 	}
 
+	poolServer := &pool.Server{Scheduler: jobScheduler, Auth: authService, PKI: authority}
+	jobScheduler.RevokeRunner = poolServer.RevokeRunner
+
+	var autoscaler *autoscale.Autoscaler
+	if cfg.Autoscale.Enabled {
+		autoscaler, err = newAutoscaler(cfg, database, authService, jobScheduler)
+		if err != nil {
+			log.Fatalf("autoscale error: %v", err)
+		}
+		apiServer.Autoscaler = autoscaler
+		go autoscaler.Run(ctx)
+	}
+
 	go authService.CleanupExpired(ctx)
+	go jobScheduler.Run(ctx)
+	go (&schedule.Runner{DB: database, Scheduler: jobScheduler}).Run(ctx)
+	go (&poll.Poller{DB: database, Scheduler: jobScheduler}).Run(ctx)
 
 	router := chi.NewRouter()
 	router.Mount("/", apiServer.Router())
@@ -108,7 +156,7 @@ func main() {
 		}
 	}()
 
-	grpcServer, grpcListener, err := startGRPC(cfg)
+	grpcServer, grpcListener, err := startGRPC(cfg, poolServer)
 	if err != nil {
 		log.Fatalf("grpc error: %v", err)
 	}
@@ -119,40 +167,61 @@ func main() {
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
+	if autoscaler != nil {
+		scaleCtx, cancelScale := context.WithTimeout(context.Background(), 30*time.Second)
+		autoscaler.Shutdown(scaleCtx)
+		cancelScale()
+	}
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
 	defer cancel()
 	_ = httpServer.Shutdown(shutdownCtx)
 }
 
-func startGRPC(cfg *config.Config) (*grpc.Server, net.Listener, error) {
-	if cfg.TLSCertPath == "" || cfg.TLSKeyPath == "" || cfg.CACertPath == "" {
-		return grpc.NewServer(), dummyListener{}, nil
+func newAutoscaler(cfg *config.Config, database *db.DB, authService *auth.Service, jobScheduler *scheduler.Scheduler) (*autoscale.Autoscaler, error) {
+	if cfg.Autoscale.Provider != "local" {
+		return nil, fmt.Errorf("unknown provider %q", cfg.Autoscale.Provider)
 	}
-
-	cert, err := tls.LoadX509KeyPair(cfg.TLSCertPath, cfg.TLSKeyPath)
-	if err != nil {
-		return nil, nil, err
+	// Local runners reach the gRPC endpoint on this host; the configured env
+	// can point them elsewhere or add TLS settings.
+	addr := cfg.PoolGRPCAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
 	}
-
-	caCertPEM, err := os.ReadFile(cfg.CACertPath)
-	if err != nil {
-		return nil, nil, err
+	env := []string{"OA_POOL_ADDR=" + addr}
+	if cfg.TLSCertPath == "" && !cfg.PKIEnabled {
+		env = append(env, "OA_POOL_INSECURE=true")
 	}
-	caPool := x509.NewCertPool()
-	if !caPool.AppendCertsFromPEM(caCertPEM) {
-		return nil, nil, fmt.Errorf("failed to parse ca cert")
+	var groups []autoscale.Group
+	for _, g := range cfg.Autoscale.Groups {
+		groups = append(groups, autoscale.Group(g))
 	}
+	return &autoscale.Autoscaler{
+		DB:        database,
+		Auth:      authService,
+		Scheduler: jobScheduler,
+		Provider:  &autoscale.Local{Command: cfg.Autoscale.Command, Env: append(env, cfg.Autoscale.Env...)},
+		Groups:    groups,
+		Interval:  cfg.Autoscale.Interval,
+	}, nil
+}
 
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    caPool,
-		MinVersion:   tls.VersionTLS13,
+func startGRPC(cfg *config.Config, poolServer *pool.Server) (*grpc.Server, net.Listener, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(poolServer.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(poolServer.StreamInterceptor),
+	}
+	if (cfg.TLSCertPath != "" && cfg.TLSKeyPath != "") || poolServer.PKI != nil {
+		tlsConfig, err := grpcTLS(cfg, poolServer.PKI)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else {
+		log.Printf("gRPC is running without TLS; runner credentials are sent in plain text")
 	}
 
-	creds := credentials.NewTLS(tlsConfig)
-	server := grpc.NewServer(grpc.Creds(creds))
-	poolpb.RegisterPoolServiceServer(server, &pool.Server{})
+	server := grpc.NewServer(opts...)
+	poolpb.RegisterPoolServiceServer(server, poolServer)
 
 	listener, err := net.Listen("tcp", cfg.PoolGRPCAddr)
 	if err != nil {
@@ -169,13 +238,39 @@ func startGRPC(cfg *config.Config) (*grpc.Server, net.Listener, error) {
 	return server, listener, nil
 }
 
-type dummyListener struct{}
-
-func (d dummyListener) Accept() (net.Conn, error) { return nil, fmt.Errorf("grpc disabled") }
-func (d dummyListener) Close() error              { return nil }
-func (d dummyListener) Addr() net.Addr            { return dummyAddr("") }
-
-type dummyAddr string
+// grpcTLS verifies runner client certificates against the configured CA
+// and the built-in one. Certificates are optional so runners holding a
+// registration token or credential can connect without one. Without a
+// configured server certificate the built-in CA issues one.
+func grpcTLS(cfg *config.Config, authority *pki.Authority) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13}
+	if cfg.TLSCertPath != "" && cfg.TLSKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertPath, cfg.TLSKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		tlsConfig.GetCertificate = authority.GetCertificate
+	}
 
-func (d dummyAddr) Network() string { return "tcp" }
-func (d dummyAddr) String() string  { return string(d) }
+	caPool := x509.NewCertPool()
+	if authority != nil {
+		caPool = authority.Pool()
+		tlsConfig.VerifyPeerCertificate = authority.VerifyPeerCertificate
+	}
+	if cfg.CACertPath != "" {
+		caCertPEM, err := os.ReadFile(cfg.CACertPath)
+		if err != nil {
+			return nil, err
+		}
+		if !caPool.AppendCertsFromPEM(caCertPEM) {
+			return nil, fmt.Errorf("failed to parse ca cert")
+		}
+	}
+	if authority != nil || cfg.CACertPath != "" {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		tlsConfig.ClientCAs = caPool
+	}
+	return tlsConfig, nil
+}
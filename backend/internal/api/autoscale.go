@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+)
+
+// scaleEventLimit is how many recent scale events GET /runners/autoscale
+// returns.
+const scaleEventLimit = 50
+
+func (s *Server) handleAutoscale(w http.ResponseWriter, r *http.Request) {
+	if s.Autoscaler == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+	events, err := s.Autoscaler.Events(r.Context(), scaleEventLimit)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":  true,
+		"provider": s.Autoscaler.Provider.Name(),
+		"groups":   s.Autoscaler.Status(),
+		"events":   events,
+	})
+}
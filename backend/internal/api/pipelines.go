@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"openaction/internal/pipeline"
+	"openaction/internal/scheduler"
+	"openaction/internal/spec"
+)
+
+func (s *Server) handleUpdateProjectSpec(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	var payload struct {
+		Spec string `json:"spec"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Spec == "" {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if _, err := spec.Parse([]byte(payload.Spec)); err != nil {
+		writeSpecError(w, err)
+		return
+	}
+	res, err := s.DB.ExecContext(r.Context(), "UPDATE projects SET pipeline_spec = ? WHERE id = ?", payload.Spec, id)
+	if err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "projects.spec", id, "updated", requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleCancelPipeline(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	result, err := s.Scheduler.CancelPipeline(r.Context(), id)
+	switch {
+	case errors.Is(err, scheduler.ErrPipelineNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrPipelineFinished):
+		http.Error(w, "pipeline already finished", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "cancel failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "pipelines.cancel", id,
+		fmt.Sprintf("cancelled %d queued jobs, stopping %d running jobs", result.Cancelled, result.Stopping), requestIP(r))
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+func (s *Server) handleRerunPipeline(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	var payload struct {
+		Mode        string `json:"mode"`
+		TriggeredBy string `json:"triggered_by"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.TriggeredBy == "" {
+		payload.TriggeredBy = "rerun"
+	}
+	attempt, err := pipeline.Rerun(r.Context(), s.DB, id, payload.Mode, payload.TriggeredBy)
+	switch {
+	case errors.Is(err, pipeline.ErrPipelineNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.Is(err, pipeline.ErrInvalidMode):
+		http.Error(w, "mode must be all or failed-only", http.StatusBadRequest)
+		return
+	case errors.Is(err, pipeline.ErrPipelineActive), errors.Is(err, pipeline.ErrNothingToRerun):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, pipeline.ErrNoSpec):
+		http.Error(w, "pipeline has no stored spec", http.StatusBadRequest)
+		return
+	case err != nil:
+		writePipelineError(w, err)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "pipelines.rerun", id, attempt.ID, requestIP(r))
+	s.supersede(r, attempt.ID)
+	writeJSON(w, http.StatusCreated, attempt)
+}
+
+// supersede applies a new pipeline's concurrency group to the older runs in
+// it and returns the runs it superseded.
+func (s *Server) supersede(r *http.Request, pipelineID string) []string {
+	result, err := s.Scheduler.Supersede(r.Context(), pipelineID)
+	if err != nil {
+		log.Printf("supersede for pipeline %s: %v", pipelineID, err)
+		return nil
+	}
+	if result == nil {
+		return nil
+	}
+	for _, id := range result.Superseded {
+		s.audit(r.Context(), identityID(r), "pipelines.supersede", id,
+			fmt.Sprintf("superseded by %s in group %s", pipelineID, result.Group), requestIP(r))
+	}
+	return result.Superseded
+}
+
+// pipelineAttempts lists every attempt of the run the pipeline belongs to,
+// oldest first.
+func (s *Server) pipelineAttempts(ctx context.Context, rootID string) ([]map[string]any, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT id,COALESCE(attempt,1),status,triggered_by,created_at,started_at,finished_at
+    FROM pipelines WHERE COALESCE(root_id,id) = ? ORDER BY attempt`, rootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []map[string]any
+	for rows.Next() {
+		var id, status, triggered string
+		var attempt int
+		var created, started, finished sql.NullInt64
+		if err := rows.Scan(&id, &attempt, &status, &triggered, &created, &started, &finished); err != nil {
+			return nil, err
+		}
+		items = append(items, map[string]any{
+			"id":           id,
+			"attempt":      attempt,
+			"status":       status,
+			"triggered_by": triggered,
+			"created_at":   created.Int64,
+			"started_at":   started.Int64,
+			"finished_at":  finished.Int64,
+		})
+	}
+	return items, rows.Err()
+}
+
+func (s *Server) handlePipelineJobs(w http.ResponseWriter, r *http.Request) {
+	pipelineID := chiURLParam(r, "id")
+	needs, err := s.pipelineJobNeeds(r.Context(), pipelineID)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	rows, err := s.DB.QueryContext(r.Context(), `
+    SELECT id,name,status,working_directory,timeout_seconds,started_at,finished_at,reused_from,
+           COALESCE(runs_on,''),COALESCE(unschedulable,0),
+           COALESCE(resource_class,''),COALESCE(cpu_millis,0),COALESCE(memory_mb,0),error
+    FROM pipeline_jobs WHERE pipeline_id = ? ORDER BY position`, pipelineID)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	var items []map[string]any
+	for rows.Next() {
+		var id, name, status, workDir, jobErr string
+		var timeout int64
+		var started, finished sql.NullInt64
+		var reusedFrom sql.NullString
+		var runsOn string
+		var unschedulable bool
+		var resources spec.Resources
+		if err := rows.Scan(&id, &name, &status, &workDir, &timeout, &started, &finished, &reusedFrom,
+			&runsOn, &unschedulable, &resources.Class, &resources.CPUMillis, &resources.MemoryMB, &jobErr); err != nil {
+			http.Error(w, "scan failed", http.StatusInternalServerError)
+			return
+		}
+		labels, _ := spec.DecodeLabels(runsOn)
+		items = append(items, map[string]any{
+			"id":                id,
+			"name":              name,
+			"status":            status,
+			"needs":             needs[id],
+			"working_directory": workDir,
+			"timeout_seconds":   timeout,
+			"started_at":        started.Int64,
+			"finished_at":       finished.Int64,
+			"reused_from":       reusedFrom.String,
+			"runs_on":           labels,
+			"unschedulable":     unschedulable && status == "queued",
+			"resources":         resources,
+			"error":             jobErr,
+		})
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) pipelineJobNeeds(ctx context.Context, pipelineID string) (map[string][]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT n.job_id, d.name
+    FROM pipeline_job_needs n
+    JOIN pipeline_jobs d ON d.id = n.needs_job_id
+    WHERE d.pipeline_id = ?`, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	needs := make(map[string][]string)
+	for rows.Next() {
+		var jobID, name string
+		if err := rows.Scan(&jobID, &name); err != nil {
+			return nil, err
+		}
+		needs[jobID] = append(needs[jobID], name)
+	}
+	return needs, rows.Err()
+}
+
+func writeSpecError(w http.ResponseWriter, err error) {
+	var list spec.ErrorList
+	if errors.As(err, &list) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"errors": list})
+		return
+	}
+	http.Error(w, "invalid pipeline spec", http.StatusUnprocessableEntity)
+}
+
+func writePipelineError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, pipeline.ErrProjectNotFound):
+		http.Error(w, "project not found", http.StatusNotFound)
+	case errors.Is(err, pipeline.ErrNoSpec):
+		http.Error(w, "missing pipeline spec", http.StatusBadRequest)
+	case errors.Is(err, pipeline.ErrInvalidPriority):
+		http.Error(w, fmt.Sprintf("priority must be between %d and %d", pipeline.MinPriority, pipeline.MaxPriority),
+			http.StatusBadRequest)
+	default:
+		var list spec.ErrorList
+		if errors.As(err, &list) {
+			writeSpecError(w, err)
+			return
+		}
+		var inputErrs spec.InputErrors
+		if errors.As(err, &inputErrs) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errors": inputErrs})
+			return
+		}
+		http.Error(w, "insert failed", http.StatusInternalServerError)
+	}
+}
+
+// handleProjectInputs describes the inputs the project's spec declares, for
+// rendering the form that starts a run.
+func (s *Server) handleProjectInputs(w http.ResponseWriter, r *http.Request) {
+	var rawSpec sql.NullString
+	err := s.DB.QueryRowContext(r.Context(), "SELECT pipeline_spec FROM projects WHERE id = ?", chiURLParam(r, "id")).
+		Scan(&rawSpec)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	items := []map[string]any{}
+	if rawSpec.String == "" {
+		writeJSON(w, http.StatusOK, map[string]any{"inputs": items})
+		return
+	}
+	parsed, err := spec.Parse([]byte(rawSpec.String))
+	if err != nil {
+		writeSpecError(w, err)
+		return
+	}
+	for _, input := range parsed.Inputs {
+		var defaultValue any = input.Default
+		switch input.Type {
+		case spec.InputBoolean:
+			defaultValue = input.Default == "true"
+		case spec.InputNumber:
+			defaultValue = json.Number(input.Default)
+		}
+		item := map[string]any{
+			"name":        input.Name,
+			"type":        input.Type,
+			"description": input.Description,
+			"required":    input.MustBeGiven(),
+			"default":     defaultValue,
+		}
+		if input.Type == spec.InputChoice {
+			item["options"] = input.Options
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"inputs": items})
+}
+
+// inputValues turns the JSON values a run is started with into strings;
+// booleans and numbers are checked against the spec later.
+func inputValues(raw map[string]any) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		case float64:
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("input %q must be a string, number or boolean", name)
+		}
+	}
+	return values, nil
+}
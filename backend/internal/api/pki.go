@@ -0,0 +1,73 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleCABundle serves the built-in CA so runners can verify the gRPC
+// endpoint. It needs no auth: the bundle holds only the public certificate.
+func (s *Server) handleCABundle(w http.ResponseWriter, r *http.Request) {
+	if s.PKI == nil {
+		http.Error(w, "built-in pki is disabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	_, _ = w.Write(s.PKI.Bundle())
+}
+
+func (s *Server) handleRunnerCertificates(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	rows, err := s.DB.QueryContext(r.Context(), `
+    SELECT serial,issued_at,expires_at,revoked_at FROM runner_certificates
+    WHERE runner_id = ? ORDER BY issued_at DESC`, id)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	now := time.Now().Unix()
+	var items []map[string]any
+	for rows.Next() {
+		var serial string
+		var issued, expires int64
+		var revoked sql.NullInt64
+		if err := rows.Scan(&serial, &issued, &expires, &revoked); err != nil {
+			http.Error(w, "scan failed", http.StatusInternalServerError)
+			return
+		}
+		state := "valid"
+		switch {
+		case revoked.Valid:
+			state = "revoked"
+		case expires <= now:
+			state = "expired"
+		}
+		items = append(items, map[string]any{
+			"serial":     serial,
+			"issued_at":  issued,
+			"expires_at": expires,
+			"revoked_at": revoked.Int64,
+			"state":      state,
+		})
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleRevokeRunnerCertificates(w http.ResponseWriter, r *http.Request) {
+	if s.PKI == nil {
+		http.Error(w, "built-in pki is disabled", http.StatusNotFound)
+		return
+	}
+	id := chiURLParam(r, "id")
+	revoked, err := s.PKI.RevokeRunner(r.Context(), id)
+	if err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "runners.certificates.revoke", id,
+		fmt.Sprintf("revoked %d certificates", revoked), requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{"revoked": revoked})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"openaction/internal/poll"
+)
+
+// projectPoll describes the project's git polling and how its last poll
+// went, or is nil when the project does not poll.
+func (s *Server) projectPoll(ctx context.Context, projectID string) (map[string]any, error) {
+	var enabled bool
+	var seconds int64
+	var branches, tags, credentials, lastError string
+	var failures int
+	var nextPoll, lastPolled, lastSuccess sql.NullInt64
+	err := s.DB.QueryRowContext(ctx, `
+    SELECT enabled,interval_seconds,branches,tags,credentials,failures,next_poll_at,last_polled_at,last_success_at,last_error
+    FROM project_polls WHERE project_id = ?`, projectID).
+		Scan(&enabled, &seconds, &branches, &tags, &credentials, &failures, &nextPoll, &lastPolled, &lastSuccess, &lastError)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	branchList, tagList := []string{}, []string{}
+	_ = json.Unmarshal([]byte(branches), &branchList)
+	_ = json.Unmarshal([]byte(tags), &tagList)
+	return map[string]any{
+		"enabled":          enabled,
+		"interval_seconds": seconds,
+		"branches":         branchList,
+		"tags":             tagList,
+		"credentials":      credentials,
+		"failures":         failures,
+		"next_poll_at":     nextPoll.Int64,
+		"last_polled_at":   lastPolled.Int64,
+		"last_success_at":  lastSuccess.Int64,
+		"last_error":       lastError,
+	}, nil
+}
+
+// handleUpdateProjectPoll sets up git polling for the project, or changes
+// it, and polls soon after. The refs seen so far are kept.
+func (s *Server) handleUpdateProjectPoll(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	var payload struct {
+		Enabled         *bool    `json:"enabled"`
+		IntervalSeconds int64    `json:"interval_seconds"`
+		Branches        []string `json:"branches"`
+		Tags            []string `json:"tags"`
+		Credentials     string   `json:"credentials"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	var repoURL, defaultBranch string
+	err := s.DB.QueryRowContext(r.Context(), "SELECT COALESCE(repo_url,''), default_branch FROM projects WHERE id = ?", id).
+		Scan(&repoURL, &defaultBranch)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	if repoURL == "" {
+		http.Error(w, "project has no repo_url", http.StatusBadRequest)
+		return
+	}
+	interval := time.Duration(payload.IntervalSeconds) * time.Second
+	if payload.IntervalSeconds == 0 {
+		interval = poll.DefaultInterval
+	}
+	if interval < poll.MinInterval {
+		http.Error(w, fmt.Sprintf("interval_seconds must be at least %d", int(poll.MinInterval.Seconds())), http.StatusBadRequest)
+		return
+	}
+	if payload.Branches == nil {
+		payload.Branches = []string{defaultBranch}
+	}
+	if payload.Tags == nil {
+		payload.Tags = []string{}
+	}
+	for _, patterns := range [][]string{payload.Branches, payload.Tags} {
+		if err := poll.ValidPatterns(patterns); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	enabled := payload.Enabled == nil || *payload.Enabled
+	branches, _ := json.Marshal(payload.Branches)
+	tags, _ := json.Marshal(payload.Tags)
+	now := time.Now().Unix()
+	if _, err := s.DB.ExecContext(r.Context(), `
+    INSERT INTO project_polls(project_id,enabled,interval_seconds,branches,tags,credentials,next_poll_at,updated_at)
+    VALUES(?,?,?,?,?,?,?,?)
+    ON CONFLICT(project_id) DO UPDATE SET enabled = excluded.enabled, interval_seconds = excluded.interval_seconds,
+      branches = excluded.branches, tags = excluded.tags, credentials = excluded.credentials, failures = 0,
+      next_poll_at = excluded.next_poll_at, updated_at = excluded.updated_at`,
+		id, enabled, int64(interval.Seconds()), string(branches), string(tags), payload.Credentials, now, now); err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "projects.poll", id,
+		fmt.Sprintf("enabled %t every %s branches %s tags %s", enabled, interval, branches, tags), requestIP(r))
+	status, err := s.projectPoll(r.Context(), id)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleDeleteProjectPoll(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	res, err := s.DB.ExecContext(r.Context(), "DELETE FROM project_polls WHERE project_id = ?", id)
+	if err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "projects.poll", id, "deleted", requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
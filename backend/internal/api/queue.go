@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"openaction/internal/pipeline"
+	"openaction/internal/scheduler"
+)
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	queue, err := s.Scheduler.Queue(r.Context())
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	if queue == nil {
+		queue = []scheduler.QueuedPipeline{}
+	}
+	writeJSON(w, http.StatusOK, queue)
+}
+
+func (s *Server) handleUpdatePipelinePriority(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	var payload struct {
+		Priority *int `json:"priority"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Priority == nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if *payload.Priority < pipeline.MinPriority || *payload.Priority > pipeline.MaxPriority {
+		http.Error(w, fmt.Sprintf("priority must be between %d and %d", pipeline.MinPriority, pipeline.MaxPriority),
+			http.StatusBadRequest)
+		return
+	}
+	var status string
+	if err := s.DB.QueryRowContext(r.Context(), "SELECT status FROM pipelines WHERE id = ?", id).Scan(&status); err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if status != "queued" && status != "running" {
+		http.Error(w, "pipeline already finished", http.StatusConflict)
+		return
+	}
+	if _, err := s.DB.ExecContext(r.Context(), "UPDATE pipelines SET priority = ? WHERE id = ?", *payload.Priority, id); err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "pipelines.priority", id, fmt.Sprintf("priority %d", *payload.Priority), requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "priority": *payload.Priority})
+}
+
+func (s *Server) handleUpdateProjectShareWeight(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	var payload struct {
+		ShareWeight int `json:"share_weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ShareWeight < 1 {
+		http.Error(w, "share_weight must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	res, err := s.DB.ExecContext(r.Context(), "UPDATE projects SET share_weight = ? WHERE id = ?", payload.ShareWeight, id)
+	if err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "projects.share_weight", id, fmt.Sprintf("share weight %d", payload.ShareWeight), requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "share_weight": payload.ShareWeight})
+}
@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -16,8 +18,14 @@ import (
 	"github.com/google/uuid"
 
 	"openaction/internal/auth"
+	"openaction/internal/autoscale"
 	"openaction/internal/blob"
 	"openaction/internal/db"
+	"openaction/internal/logstream"
+	"openaction/internal/pipeline"
+	"openaction/internal/pki"
+	"openaction/internal/scheduler"
+	"openaction/internal/spec"
 	"openaction/internal/ws"
 )
 
@@ -25,6 +33,10 @@ type Server struct {
 	DB         *db.DB
 	Auth       *auth.Service
 	Blob       *blob.Store
+	Logs       *logstream.Broker
+	Scheduler  *scheduler.Scheduler
+	PKI        *pki.Authority
+	Autoscaler *autoscale.Autoscaler
 	DataDir    string
 	SecureOnly bool
 	SecretKey  []byte
@@ -43,12 +55,15 @@ func (s *Server) Router() http.Handler {
 		r.Get("/latest/{name}", s.handlePublicLatest)
 	})
 
+	r.Post("/hooks/{provider}/{project}", s.handleWebhook)
+
 	r.Route("/actions", func(r chi.Router) {
 		r.Post("/auth/login", s.handleLogin)
 		r.Post("/auth/logout", s.handleLogout)
 		r.Get("/auth/tokens", s.handleListTokens)
 		r.Post("/auth/tokens", s.handleCreateToken)
 		r.Delete("/auth/tokens/{id}", s.handleDeleteToken)
+		r.Get("/pki/ca.crt", s.handleCABundle)
 
 		r.Group(func(r chi.Router) {
 			r.Use(s.Auth.Middleware)
@@ -59,9 +74,25 @@ func (s *Server) Router() http.Handler {
 			r.With(s.requirePermission("projects.read")).Get("/projects", s.handleProjects)
 			r.With(s.requirePermission("projects.write")).Post("/projects", s.handleCreateProject)
 			r.With(s.requirePermission("projects.read")).Get("/projects/{id}", s.handleProject)
+			r.With(s.requirePermission("projects.write")).Put("/projects/{id}/spec", s.handleUpdateProjectSpec)
+			r.With(s.requirePermission("projects.write")).Put("/projects/{id}/share-weight", s.handleUpdateProjectShareWeight)
+			r.With(s.requirePermission("projects.write")).Put("/projects/{id}/poll", s.handleUpdateProjectPoll)
+			r.With(s.requirePermission("projects.write")).Delete("/projects/{id}/poll", s.handleDeleteProjectPoll)
 			r.With(s.requirePermission("pipelines.read")).Get("/projects/{id}/pipelines", s.handleProjectPipelines)
+			r.With(s.requirePermission("projects.read")).Get("/projects/{id}/webhooks/deliveries", s.handleWebhookDeliveries)
+			r.With(s.requirePermission("projects.read")).Get("/projects/{id}/schedules", s.handleSchedules)
+			r.With(s.requirePermission("projects.write")).Post("/projects/{id}/schedules", s.handleCreateSchedule)
+			r.With(s.requirePermission("projects.read")).Get("/projects/{id}/schedules/{scheduleID}", s.handleSchedule)
+			r.With(s.requirePermission("projects.write")).Put("/projects/{id}/schedules/{scheduleID}", s.handleUpdateSchedule)
+			r.With(s.requirePermission("projects.write")).Delete("/projects/{id}/schedules/{scheduleID}", s.handleDeleteSchedule)
 			r.With(s.requirePermission("pipelines.write")).Post("/projects/{id}/pipelines", s.handleCreatePipeline)
+			r.With(s.requirePermission("projects.read")).Get("/projects/{id}/inputs", s.handleProjectInputs)
 			r.With(s.requirePermission("pipelines.read")).Get("/pipelines/{id}", s.handlePipeline)
+			r.With(s.requirePermission("pipelines.write")).Post("/pipelines/{id}/cancel", s.handleCancelPipeline)
+			r.With(s.requirePermission("pipelines.write")).Post("/pipelines/{id}/rerun", s.handleRerunPipeline)
+			r.With(s.requirePermission("pipelines.write")).Put("/pipelines/{id}/priority", s.handleUpdatePipelinePriority)
+			r.With(s.requirePermission("pipelines.read")).Get("/queue", s.handleQueue)
+			r.With(s.requirePermission("pipelines.read")).Get("/pipelines/{id}/jobs", s.handlePipelineJobs)
 			r.With(s.requirePermission("pipelines.read")).Get("/pipelines/{id}/steps", s.handlePipelineSteps)
 			r.With(s.requirePermission("logs.read")).Get("/pipelines/{id}/logs", s.handlePipelineLogs)
 			r.With(s.requirePermission("logs.read")).Get("/pipelines/{id}/logs/stream", wsHandler(s))
@@ -83,7 +114,17 @@ func (s *Server) Router() http.Handler {
 			r.With(s.requirePermission("runners.write")).Post("/runners", s.handleCreateRunner)
 			r.With(s.requirePermission("runners.write")).Put("/runners/{id}", s.handleUpdateRunner)
 			r.With(s.requirePermission("runners.write")).Delete("/runners/{id}", s.handleDeleteRunner)
+			r.With(s.requirePermission("runners.write")).Post("/runners/{id}/drain", s.handleDrainRunner)
+			r.With(s.requirePermission("runners.write")).Post("/runners/{id}/resume", s.handleResumeRunner)
 			r.With(s.requirePermission("runners.read")).Get("/runners/summary", s.handleRunnerSummary)
+			r.With(s.requirePermission("runners.read")).Get("/runners/autoscale", s.handleAutoscale)
+			r.With(s.requirePermission("runners.write")).Get("/runners/tokens", s.handleRegistrationTokens)
+			r.With(s.requirePermission("runners.write")).Post("/runners/tokens", s.handleCreateRegistrationToken)
+			r.With(s.requirePermission("runners.write")).Delete("/runners/tokens/{id}", s.handleRevokeRegistrationToken)
+			r.With(s.requirePermission("runners.write")).Delete("/runners/{id}/credentials", s.handleRevokeRunnerCredentials)
+			r.With(s.requirePermission("runners.read")).Get("/runners/{id}/certificates", s.handleRunnerCertificates)
+			r.With(s.requirePermission("runners.read")).Get("/runners/{id}/telemetry", s.handleRunnerTelemetry)
+			r.With(s.requirePermission("runners.write")).Delete("/runners/{id}/certificates", s.handleRevokeRunnerCertificates)
 
 			r.With(s.requirePermission("env.read")).Get("/environments", s.handleEnvironments)
 			r.With(s.requirePermission("env.write")).Post("/environments", s.handleCreateEnvironment)
@@ -236,7 +277,8 @@ func (s *Server) handleDeleteToken(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
-	rows, err := s.DB.QueryContext(r.Context(), "SELECT id,name,repo_url,default_branch,created_at FROM projects ORDER BY created_at DESC")
+	rows, err := s.DB.QueryContext(r.Context(),
+		"SELECT id,name,repo_url,default_branch,created_at,COALESCE(share_weight,1) FROM projects ORDER BY created_at DESC")
 	if err != nil {
 		http.Error(w, "query failed", http.StatusInternalServerError)
 		return
@@ -246,7 +288,8 @@ func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var id, name, repo, branch string
 		var created int64
-		if err := rows.Scan(&id, &name, &repo, &branch, &created); err != nil {
+		var shareWeight int
+		if err := rows.Scan(&id, &name, &repo, &branch, &created, &shareWeight); err != nil {
 			http.Error(w, "scan failed", http.StatusInternalServerError)
 			return
 		}
@@ -256,6 +299,7 @@ func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
 			"repo_url":       repo,
 			"default_branch": branch,
 			"created_at":     created,
+			"share_weight":   shareWeight,
 		})
 	}
 	writeJSON(w, http.StatusOK, items)
@@ -266,18 +310,33 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 		Name          string `json:"name"`
 		RepoURL       string `json:"repo_url"`
 		DefaultBranch string `json:"default_branch"`
+		PipelineSpec  string `json:"pipeline_spec"`
+		ShareWeight   int    `json:"share_weight"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.ShareWeight < 0 {
 		http.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
+	if payload.RepoURL != "" && !validRepoURL(payload.RepoURL) {
+		http.Error(w, "repo_url must be an http, https, ssh, git or file URL", http.StatusBadRequest)
+		return
+	}
 	if payload.DefaultBranch == "" {
 		payload.DefaultBranch = "main"
 	}
+	if payload.ShareWeight == 0 {
+		payload.ShareWeight = 1
+	}
+	if payload.PipelineSpec != "" {
+		if _, err := spec.Parse([]byte(payload.PipelineSpec)); err != nil {
+			writeSpecError(w, err)
+			return
+		}
+	}
 	id := uuid.NewString()
 	_, err := s.DB.ExecContext(r.Context(),
-		"INSERT INTO projects(id,name,repo_url,default_branch,created_at) VALUES(?,?,?,?,?)",
-		id, payload.Name, payload.RepoURL, payload.DefaultBranch, time.Now().Unix())
+		"INSERT INTO projects(id,name,repo_url,default_branch,pipeline_spec,created_at,share_weight) VALUES(?,?,?,?,?,?,?)",
+		id, payload.Name, payload.RepoURL, payload.DefaultBranch, payload.PipelineSpec, time.Now().Unix(), payload.ShareWeight)
 	if err != nil {
 		http.Error(w, "insert failed", http.StatusInternalServerError)
 		return
@@ -286,31 +345,63 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
 }
 
+// scpRepoURL matches the scp-like ssh form git accepts, user@host:path.
+var scpRepoURL = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:[^-]`)
+
+// validRepoURL reports whether raw is a repository URL the poller and the
+// runners may hand to git.
+func validRepoURL(raw string) bool {
+	if scpRepoURL.MatchString(raw) {
+		return true
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh", "git":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	}
+	return false
+}
+
 func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var name, repo, branch string
+	var pipelineSpec sql.NullString
 	var created int64
+	var shareWeight int
 	err := s.DB.QueryRowContext(r.Context(),
-		"SELECT id,name,repo_url,default_branch,created_at FROM projects WHERE id = ?", id).
-		Scan(&id, &name, &repo, &branch, &created)
+		"SELECT id,name,repo_url,default_branch,pipeline_spec,created_at,COALESCE(share_weight,1) FROM projects WHERE id = ?", id).
+		Scan(&id, &name, &repo, &branch, &pipelineSpec, &created, &shareWeight)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
+	poll, err := s.projectPoll(r.Context(), id)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"id":             id,
 		"name":           name,
 		"repo_url":       repo,
 		"default_branch": branch,
+		"pipeline_spec":  pipelineSpec.String,
 		"created_at":     created,
+		"share_weight":   shareWeight,
+		"poll":           poll,
 	})
 }
 
 func (s *Server) handleProjectPipelines(w http.ResponseWriter, r *http.Request) {
 	projectID := chi.URLParam(r, "id")
 	rows, err := s.DB.QueryContext(r.Context(), `
-    SELECT id,status,commit_hash,branch,triggered_by,started_at,finished_at
-    FROM pipelines WHERE project_id = ? ORDER BY started_at DESC`, projectID)
+    SELECT id,status,commit_hash,branch,triggered_by,started_at,finished_at,COALESCE(attempt,1),COALESCE(priority,0)
+    FROM pipelines WHERE project_id = ? ORDER BY COALESCE(created_at, started_at) DESC`, projectID)
 	if err != nil {
 		http.Error(w, "query failed", http.StatusInternalServerError)
 		return
@@ -320,7 +411,8 @@ func (s *Server) handleProjectPipelines(w http.ResponseWriter, r *http.Request)
 	for rows.Next() {
 		var id, status, commit, branch, triggered string
 		var started, finished sql.NullInt64
-		if err := rows.Scan(&id, &status, &commit, &branch, &triggered, &started, &finished); err != nil {
+		var attempt, priority int
+		if err := rows.Scan(&id, &status, &commit, &branch, &triggered, &started, &finished, &attempt, &priority); err != nil {
 			http.Error(w, "scan failed", http.StatusInternalServerError)
 			return
 		}
@@ -332,6 +424,8 @@ func (s *Server) handleProjectPipelines(w http.ResponseWriter, r *http.Request)
 			"triggered_by": triggered,
 			"started_at":   started.Int64,
 			"finished_at":  finished.Int64,
+			"attempt":      attempt,
+			"priority":     priority,
 		})
 	}
 	writeJSON(w, http.StatusOK, items)
@@ -343,59 +437,99 @@ func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
 		CommitHash  string `json:"commit_hash"`
 		Branch      string `json:"branch"`
 		TriggeredBy string `json:"triggered_by"`
+		Spec        string `json:"spec"`
+		Priority    int    `json:"priority"`
+
+		Inputs map[string]any `json:"inputs"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "invalid payload", http.StatusBadRequest)
 		return
 	}
+	inputs, err := inputValues(payload.Inputs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if payload.Branch == "" {
 		payload.Branch = "main"
 	}
 	if payload.TriggeredBy == "" {
 		payload.TriggeredBy = "manual"
 	}
-	id := uuid.NewString()
-	_, err := s.DB.ExecContext(r.Context(), `
-    INSERT INTO pipelines(id,project_id,status,commit_hash,branch,triggered_by,started_at)
-    VALUES(?,?,?,?,?,?,?)`,
-		id, projectID, "queued", payload.CommitHash, payload.Branch, payload.TriggeredBy, time.Now().Unix())
+	id, err := pipeline.Create(r.Context(), s.DB, pipeline.Run{
+		ProjectID:   projectID,
+		CommitHash:  payload.CommitHash,
+		Branch:      payload.Branch,
+		TriggeredBy: payload.TriggeredBy,
+		RawSpec:     payload.Spec,
+		Priority:    payload.Priority,
+		Inputs:      inputs,
+	})
 	if err != nil {
-		http.Error(w, "insert failed", http.StatusInternalServerError)
+		writePipelineError(w, err)
 		return
 	}
 	s.audit(r.Context(), identityID(r), "pipelines.create", projectID, id, requestIP(r))
-	writeJSON(w, http.StatusCreated, map[string]any{"id": id})
+	response := map[string]any{"id": id}
+	if superseded := s.supersede(r, id); len(superseded) > 0 {
+		response["superseded"] = superseded
+	}
+	writeJSON(w, http.StatusCreated, response)
 }
 
 func (s *Server) handlePipeline(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	var projectID, status, commit, branch, triggered string
+	var projectID, status, commit, branch, triggered, rootID string
+	var attempt, priority int
 	var started, finished sql.NullInt64
+	var rerunOf, supersededBy sql.NullString
+	var group, rawInputs string
 	err := s.DB.QueryRowContext(r.Context(), `
-    SELECT project_id,status,commit_hash,branch,triggered_by,started_at,finished_at
+    SELECT project_id,status,commit_hash,branch,triggered_by,started_at,finished_at,
+           COALESCE(attempt,1),rerun_of,COALESCE(root_id,id),COALESCE(priority,0),
+           COALESCE(concurrency_group,''),superseded_by,COALESCE(inputs,'')
     FROM pipelines WHERE id = ?`, id).
-		Scan(&projectID, &status, &commit, &branch, &triggered, &started, &finished)
+		Scan(&projectID, &status, &commit, &branch, &triggered, &started, &finished, &attempt, &rerunOf, &rootID, &priority,
+			&group, &supersededBy, &rawInputs)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
+	attempts, err := s.pipelineAttempts(r.Context(), rootID)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	inputs := map[string]string{}
+	if rawInputs != "" {
+		_ = json.Unmarshal([]byte(rawInputs), &inputs)
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"id":           id,
-		"project_id":   projectID,
-		"status":       status,
-		"commit_hash":  commit,
-		"branch":       branch,
-		"triggered_by": triggered,
-		"started_at":   started.Int64,
-		"finished_at":  finished.Int64,
+		"id":                id,
+		"project_id":        projectID,
+		"status":            status,
+		"commit_hash":       commit,
+		"branch":            branch,
+		"triggered_by":      triggered,
+		"started_at":        started.Int64,
+		"finished_at":       finished.Int64,
+		"attempt":           attempt,
+		"rerun_of":          rerunOf.String,
+		"root_id":           rootID,
+		"attempts":          attempts,
+		"priority":          priority,
+		"concurrency_group": group,
+		"superseded_by":     supersededBy.String,
+		"inputs":            inputs,
 	})
 }
 
 func (s *Server) handlePipelineSteps(w http.ResponseWriter, r *http.Request) {
 	pipelineID := chi.URLParam(r, "id")
 	rows, err := s.DB.QueryContext(r.Context(), `
-    SELECT id,name,status,started_at,finished_at,log_path
-    FROM pipeline_steps WHERE pipeline_id = ? ORDER BY started_at`, pipelineID)
+    SELECT id,job_id,name,status,started_at,finished_at,log_path
+    FROM pipeline_steps WHERE pipeline_id = ? ORDER BY position, started_at`, pipelineID)
 	if err != nil {
 		http.Error(w, "query failed", http.StatusInternalServerError)
 		return
@@ -405,13 +539,14 @@ func (s *Server) handlePipelineSteps(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var id, name, status string
 		var started, finished sql.NullInt64
-		var logPath sql.NullString
-		if err := rows.Scan(&id, &name, &status, &started, &finished, &logPath); err != nil {
+		var jobID, logPath sql.NullString
+		if err := rows.Scan(&id, &jobID, &name, &status, &started, &finished, &logPath); err != nil {
 			http.Error(w, "scan failed", http.StatusInternalServerError)
 			return
 		}
 		items = append(items, map[string]any{
 			"id":          id,
+			"job_id":      jobID.String,
 			"name":        name,
 			"status":      status,
 			"started_at":  started.Int64,
@@ -711,7 +846,17 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 func wsHandler(s *Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		secrets := s.secretValues(r.Context())
+		pipelineID := chiURLParam(r, "id")
 		handler := &ws.LogHandler{
+			Broker: s.Logs,
+			Lookup: func(ctx context.Context, stepID string) (string, string, error) {
+				var logPath sql.NullString
+				var status string
+				err := s.DB.QueryRowContext(ctx,
+					"SELECT log_path,status FROM pipeline_steps WHERE id = ? AND pipeline_id = ?", stepID, pipelineID).
+					Scan(&logPath, &status)
+				return logPath.String, status, err
+			},
 			Open: func(ctx context.Context, logPath string) (io.ReadCloser, error) {
 				return s.Blob.ReadDecompressed(logPath)
 			},
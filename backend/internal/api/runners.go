@@ -2,13 +2,25 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
+
+	"openaction/internal/scheduler"
 )
 
 func (s *Server) handleRunners(w http.ResponseWriter, r *http.Request) {
-	rows, err := s.DB.QueryContext(r.Context(), "SELECT id,name,status,version,last_seen,created_at FROM runners ORDER BY created_at DESC")
+	rows, err := s.DB.QueryContext(r.Context(), `
+    SELECT id,name,status,version,last_seen,created_at,
+           COALESCE(hostname,''),COALESCE(os,''),COALESCE(arch,''),COALESCE(address,''),
+           COALESCE(slots,1),COALESCE(cpu_millis,0),COALESCE(memory_mb,0),COALESCE(ephemeral,0),
+           COALESCE(healthy,1),COALESCE(unhealthy_reason,''),
+           (SELECT COUNT(1) FROM pipeline_jobs j WHERE j.runner_id = runners.id AND j.status = 'running')
+    FROM runners ORDER BY created_at DESC`)
 	if err != nil {
 		http.Error(w, "query failed", http.StatusInternalServerError)
 		return
@@ -16,22 +28,48 @@ func (s *Server) handleRunners(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 	var items []map[string]any
 	for rows.Next() {
-		var id, name, status, version string
+		var id, name, status, version, hostname, osName, arch, address string
 		var lastSeen, created int64
-		if err := rows.Scan(&id, &name, &status, &version, &lastSeen, &created); err != nil {
+		var slots, slotsUsed, cpuMillis, memoryMB int
+		var ephemeral, healthy bool
+		var unhealthyReason string
+		if err := rows.Scan(&id, &name, &status, &version, &lastSeen, &created,
+			&hostname, &osName, &arch, &address, &slots, &cpuMillis, &memoryMB, &ephemeral,
+			&healthy, &unhealthyReason, &slotsUsed); err != nil {
 			http.Error(w, "scan failed", http.StatusInternalServerError)
 			return
 		}
-		tags := s.runnerTags(r.Context(), id)
-		items = append(items, map[string]any{
+		item := map[string]any{
 			"id":         id,
 			"name":       name,
 			"status":     status,
 			"version":    version,
 			"last_seen":  lastSeen,
 			"created_at": created,
-			"tags":       tags,
-		})
+			"hostname":   hostname,
+			"os":         osName,
+			"arch":       arch,
+			"address":    address,
+			"slots":      slots,
+			"slots_used": slotsUsed,
+			"cpu_millis": cpuMillis,
+			"memory_mb":  memoryMB,
+			"ephemeral":  ephemeral,
+			"healthy":    healthy,
+		}
+		if unhealthyReason != "" {
+			item["unhealthy_reason"] = unhealthyReason
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	// Tags are read once the runner rows are closed; the database has a
+	// single connection.
+	for _, item := range items {
+		item["tags"] = s.runnerTags(r.Context(), item["id"].(string))
+		if group := s.Autoscaler.GroupOf(item["id"].(string)); group != "" {
+			item["autoscale_group"] = group
+		}
 	}
 	writeJSON(w, http.StatusOK, items)
 }
@@ -97,6 +135,41 @@ func (s *Server) handleUpdateRunner(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+func (s *Server) handleDrainRunner(w http.ResponseWriter, r *http.Request) {
+	s.setRunnerDraining(w, r, true)
+}
+
+func (s *Server) handleResumeRunner(w http.ResponseWriter, r *http.Request) {
+	s.setRunnerDraining(w, r, false)
+}
+
+func (s *Server) setRunnerDraining(w http.ResponseWriter, r *http.Request, draining bool) {
+	id := chiURLParam(r, "id")
+	action, change := "runners.resume", s.Scheduler.Resume
+	if draining {
+		action, change = "runners.drain", s.Scheduler.Drain
+	}
+	err := change(r.Context(), id)
+	if errors.Is(err, scheduler.ErrRunnerNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	var status string
+	var running int
+	if err := s.DB.QueryRowContext(r.Context(), `
+    SELECT status, (SELECT COUNT(1) FROM pipeline_jobs j WHERE j.runner_id = runners.id AND j.status = 'running')
+    FROM runners WHERE id = ?`, id).Scan(&status, &running); err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), action, id, status, requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{"id": id, "status": status, "running_jobs": running})
+}
+
 func (s *Server) handleDeleteRunner(w http.ResponseWriter, r *http.Request) {
 	id := chiURLParam(r, "id")
 	_, err := s.DB.ExecContext(r.Context(), "DELETE FROM runners WHERE id = ?", id)
@@ -104,6 +177,20 @@ func (s *Server) handleDeleteRunner(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "delete failed", http.StatusInternalServerError)
 		return
 	}
+	if _, err := s.DB.ExecContext(r.Context(), "DELETE FROM runner_telemetry WHERE runner_id = ?", id); err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.Auth.RevokeRunnerCredentials(r.Context(), id); err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	if s.PKI != nil {
+		if _, err := s.PKI.RevokeRunner(r.Context(), id); err != nil {
+			http.Error(w, "delete failed", http.StatusInternalServerError)
+			return
+		}
+	}
 	s.audit(r.Context(), identityID(r), "runners.delete", id, "deleted", requestIP(r))
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -114,11 +201,66 @@ func (s *Server) handleRunnerSummary(w http.ResponseWriter, r *http.Request) {
 	_ = s.DB.QueryRowContext(r.Context(), "SELECT COUNT(1) FROM runners WHERE status IN ('online','busy')").Scan(&online)
 	_ = s.DB.QueryRowContext(r.Context(), "SELECT COUNT(1) FROM runners WHERE status = 'busy'").Scan(&busy)
 	_ = s.DB.QueryRowContext(r.Context(), "SELECT COUNT(1) FROM runners WHERE status = 'offline'").Scan(&offline)
+	var draining int
+	_ = s.DB.QueryRowContext(r.Context(), "SELECT COUNT(1) FROM runners WHERE status IN ('draining','drained')").Scan(&draining)
+	var unhealthy int
+	_ = s.DB.QueryRowContext(r.Context(), `
+    SELECT COUNT(1) FROM runners WHERE status != 'offline' AND NOT COALESCE(healthy,1)`).Scan(&unhealthy)
+	var slotsTotal, slotsUsed int
+	_ = s.DB.QueryRowContext(r.Context(), `
+    SELECT COALESCE(SUM(MAX(COALESCE(slots,1),1)),0) FROM runners WHERE status IN ('online','busy')`).Scan(&slotsTotal)
+	_ = s.DB.QueryRowContext(r.Context(), `
+    SELECT COUNT(1) FROM pipeline_jobs j JOIN runners r ON r.id = j.runner_id
+    WHERE j.status = 'running' AND r.status IN ('online','busy')`).Scan(&slotsUsed)
 	writeJSON(w, http.StatusOK, map[string]any{
-		"total":   total,
-		"online":  online,
-		"busy":    busy,
-		"offline": offline,
+		"total":       total,
+		"online":      online,
+		"busy":        busy,
+		"offline":     offline,
+		"draining":    draining,
+		"unhealthy":   unhealthy,
+		"slots_total": slotsTotal,
+		"slots_used":  slotsUsed,
+		"slots_free":  max(slotsTotal-slotsUsed, 0),
+	})
+}
+
+// telemetryLimit caps the samples GET /runners/{id}/telemetry returns.
+const telemetryLimit = 240
+
+func (s *Server) handleRunnerTelemetry(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	var healthy bool
+	var reason string
+	err := s.DB.QueryRowContext(r.Context(),
+		"SELECT COALESCE(healthy,1), COALESCE(unhealthy_reason,'') FROM runners WHERE id = ?", id).Scan(&healthy, &reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "runner not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	limit := telemetryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, telemetryLimit)
+	}
+	samples, err := s.Scheduler.Telemetry(r.Context(), id, limit)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"runner_id":        id,
+		"healthy":          healthy,
+		"unhealthy_reason": reason,
+		"samples":          samples,
 	})
 }
 
@@ -149,3 +291,103 @@ func (s *Server) storeRunnerTags(ctx context.Context, runnerID string, tags []st
 			randomID(), runnerID, tag)
 	}
 }
+
+func (s *Server) handleCreateRegistrationToken(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Description string `json:"description"`
+		ExpiresIn   string `json:"expires_in"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+	ttl := time.Hour
+	if payload.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(payload.ExpiresIn)
+		if err != nil || parsed <= 0 || parsed > 30*24*time.Hour {
+			http.Error(w, "expires_in must be a duration up to 720h", http.StatusBadRequest)
+			return
+		}
+		ttl = parsed
+	}
+	token, err := s.Auth.CreateRegistrationToken(r.Context(), payload.Description, identityID(r), ttl)
+	if err != nil {
+		http.Error(w, "insert failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "runners.tokens.create", token.ID, payload.Description, requestIP(r))
+	writeJSON(w, http.StatusCreated, token)
+}
+
+func (s *Server) handleRegistrationTokens(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.DB.QueryContext(r.Context(), `
+    SELECT id,description,created_by,created_at,expires_at,used_at,COALESCE(runner_id,''),revoked_at
+    FROM runner_registration_tokens ORDER BY created_at DESC LIMIT 200`)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	now := time.Now().Unix()
+	var items []map[string]any
+	for rows.Next() {
+		var id, description, createdBy, runnerID string
+		var created, expires int64
+		var used, revoked sql.NullInt64
+		if err := rows.Scan(&id, &description, &createdBy, &created, &expires, &used, &runnerID, &revoked); err != nil {
+			http.Error(w, "scan failed", http.StatusInternalServerError)
+			return
+		}
+		state := "active"
+		switch {
+		case used.Valid:
+			state = "used"
+		case revoked.Valid:
+			state = "revoked"
+		case expires <= now:
+			state = "expired"
+		}
+		items = append(items, map[string]any{
+			"id":          id,
+			"description": description,
+			"created_by":  createdBy,
+			"created_at":  created,
+			"expires_at":  expires,
+			"used_at":     used.Int64,
+			"runner_id":   runnerID,
+			"state":       state,
+		})
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleRevokeRegistrationToken(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	revoked, err := s.Auth.RevokeRegistrationToken(r.Context(), id)
+	if err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		http.Error(w, "no unused token with this id", http.StatusNotFound)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "runners.tokens.revoke", id, "revoked", requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleRevokeRunnerCredentials cuts a runner off: its calls are refused and
+// an open job stream is closed at its next heartbeat.
+func (s *Server) handleRevokeRunnerCredentials(w http.ResponseWriter, r *http.Request) {
+	id := chiURLParam(r, "id")
+	revoked, err := s.Auth.RevokeRunnerCredentials(r.Context(), id)
+	if err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "runners.credentials.revoke", id,
+		fmt.Sprintf("revoked %d credentials", revoked), requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]any{"revoked": revoked})
+}
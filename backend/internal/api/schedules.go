@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"openaction/internal/schedule"
+)
+
+// nextRunsShown is how many upcoming fire times a schedule lists.
+const nextRunsShown = 5
+
+type schedulePayload struct {
+	Name     *string            `json:"name"`
+	Cron     *string            `json:"cron"`
+	Timezone *string            `json:"timezone"`
+	Branch   *string            `json:"branch"`
+	Inputs   *map[string]string `json:"inputs"`
+	Enabled  *bool              `json:"enabled"`
+	Missed   *string            `json:"missed"`
+}
+
+type scheduleRow struct {
+	id, projectID, name, cron, timezone, branch, inputs, missed string
+	enabled                                                     bool
+	nextRunAt, lastRunAt                                        sql.NullInt64
+	lastPipelineID, lastError                                   string
+	createdAt, updatedAt                                        int64
+}
+
+const scheduleColumns = `id,project_id,name,cron,timezone,branch,inputs,missed,enabled,next_run_at,last_run_at,
+           last_pipeline_id,last_error,created_at,updated_at`
+
+func scanSchedule(scan func(...any) error) (*scheduleRow, error) {
+	var row scheduleRow
+	err := scan(&row.id, &row.projectID, &row.name, &row.cron, &row.timezone, &row.branch, &row.inputs, &row.missed,
+		&row.enabled, &row.nextRunAt, &row.lastRunAt, &row.lastPipelineID, &row.lastError, &row.createdAt, &row.updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (row *scheduleRow) item() map[string]any {
+	inputs := map[string]string{}
+	_ = json.Unmarshal([]byte(row.inputs), &inputs)
+	nextRuns := []int64{}
+	if sched, loc, err := schedule.Parse(row.cron, row.timezone); err == nil && row.enabled {
+		for _, t := range schedule.NextRuns(sched, loc, time.Now(), nextRunsShown) {
+			nextRuns = append(nextRuns, t.Unix())
+		}
+	}
+	return map[string]any{
+		"id":               row.id,
+		"project_id":       row.projectID,
+		"name":             row.name,
+		"cron":             row.cron,
+		"timezone":         row.timezone,
+		"branch":           row.branch,
+		"inputs":           inputs,
+		"enabled":          row.enabled,
+		"missed":           row.missed,
+		"next_run_at":      row.nextRunAt.Int64,
+		"next_runs":        nextRuns,
+		"last_run_at":      row.lastRunAt.Int64,
+		"last_pipeline_id": row.lastPipelineID,
+		"last_error":       row.lastError,
+		"created_at":       row.createdAt,
+		"updated_at":       row.updatedAt,
+	}
+}
+
+// apply sets the payload's fields on the row and checks the result.
+func (p *schedulePayload) apply(row *scheduleRow) error {
+	if p.Name != nil {
+		row.name = *p.Name
+	}
+	if p.Cron != nil {
+		row.cron = *p.Cron
+	}
+	if p.Timezone != nil {
+		row.timezone = *p.Timezone
+	}
+	if p.Branch != nil && *p.Branch != "" {
+		row.branch = *p.Branch
+	}
+	if p.Inputs != nil {
+		raw, err := json.Marshal(*p.Inputs)
+		if err != nil {
+			return err
+		}
+		row.inputs = string(raw)
+	}
+	if p.Enabled != nil {
+		row.enabled = *p.Enabled
+	}
+	if p.Missed != nil {
+		row.missed = *p.Missed
+	}
+	if row.missed != schedule.Skip && row.missed != schedule.CatchUp {
+		return schedule.ErrInvalidPolicy
+	}
+	_, _, err := schedule.Parse(row.cron, row.timezone)
+	return err
+}
+
+// firstRun is when the row's schedule fires next, from now on, or NULL when
+// it is disabled or never fires.
+func (row *scheduleRow) firstRun() sql.NullInt64 {
+	sched, loc, err := schedule.Parse(row.cron, row.timezone)
+	if err != nil || !row.enabled {
+		return sql.NullInt64{}
+	}
+	runs := schedule.NextRuns(sched, loc, time.Now(), 1)
+	if len(runs) == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: runs[0].Unix(), Valid: true}
+}
+
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.DB.QueryContext(r.Context(),
+		"SELECT "+scheduleColumns+" FROM pipeline_schedules WHERE project_id = ? ORDER BY created_at", chiURLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	items := []map[string]any{}
+	for rows.Next() {
+		row, err := scanSchedule(rows.Scan)
+		if err != nil {
+			http.Error(w, "scan failed", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, row.item())
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	projectID := chiURLParam(r, "id")
+	var payload schedulePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Cron == nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	row := &scheduleRow{id: randomID(), projectID: projectID, timezone: "UTC", inputs: "{}", missed: schedule.Skip, enabled: true}
+	err := s.DB.QueryRowContext(r.Context(), "SELECT default_branch FROM projects WHERE id = ?", projectID).Scan(&row.branch)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "project not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	if err := payload.apply(row); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	row.nextRunAt = row.firstRun()
+	now := time.Now().Unix()
+	row.createdAt, row.updatedAt = now, now
+	if _, err := s.DB.ExecContext(r.Context(), `
+    INSERT INTO pipeline_schedules(id,project_id,name,cron,timezone,branch,inputs,enabled,missed,next_run_at,created_at,updated_at)
+    VALUES(?,?,?,?,?,?,?,?,?,?,?,?)`,
+		row.id, row.projectID, row.name, row.cron, row.timezone, row.branch, row.inputs, row.enabled, row.missed,
+		row.nextRunAt, now, now); err != nil {
+		http.Error(w, "insert failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "schedules.create", row.id, projectID+" "+row.cron, requestIP(r))
+	writeJSON(w, http.StatusCreated, row.item())
+}
+
+func (s *Server) loadSchedule(ctx context.Context, r *http.Request) (*scheduleRow, error) {
+	return scanSchedule(s.DB.QueryRowContext(ctx,
+		"SELECT "+scheduleColumns+" FROM pipeline_schedules WHERE id = ? AND project_id = ?",
+		chiURLParam(r, "scheduleID"), chiURLParam(r, "id")).Scan)
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	row, err := s.loadSchedule(r.Context(), r)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, row.item())
+}
+
+// handleUpdateSchedule changes the fields given and reschedules from now.
+func (s *Server) handleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	row, err := s.loadSchedule(r.Context(), r)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	var payload schedulePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := payload.apply(row); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	row.nextRunAt = row.firstRun()
+	row.updatedAt = time.Now().Unix()
+	if _, err := s.DB.ExecContext(r.Context(), `
+    UPDATE pipeline_schedules SET name = ?, cron = ?, timezone = ?, branch = ?, inputs = ?, enabled = ?, missed = ?,
+      next_run_at = ?, updated_at = ?
+    WHERE id = ?`,
+		row.name, row.cron, row.timezone, row.branch, row.inputs, row.enabled, row.missed,
+		row.nextRunAt, row.updatedAt, row.id); err != nil {
+		http.Error(w, "update failed", http.StatusInternalServerError)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "schedules.update", row.id, row.projectID+" "+row.cron, requestIP(r))
+	writeJSON(w, http.StatusOK, row.item())
+}
+
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	res, err := s.DB.ExecContext(r.Context(), "DELETE FROM pipeline_schedules WHERE id = ? AND project_id = ?",
+		chiURLParam(r, "scheduleID"), chiURLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "delete failed", http.StatusInternalServerError)
+		return
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.audit(r.Context(), identityID(r), "schedules.delete", chiURLParam(r, "scheduleID"), "deleted", requestIP(r))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
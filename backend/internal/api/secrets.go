@@ -127,3 +127,15 @@ func (s *Server) secretValues(ctx context.Context) []string {
 	}
 	return values
 }
+
+// projectSecret decrypts the secret scoped to the project, project:<id>.
+// It returns sql.ErrNoRows when there is none.
+func (s *Server) projectSecret(ctx context.Context, projectID, name string) (string, error) {
+	var enc string
+	err := s.DB.QueryRowContext(ctx,
+		"SELECT value_enc FROM secrets WHERE name = ? AND scope = ?", name, "project:"+projectID).Scan(&enc)
+	if err != nil {
+		return "", err
+	}
+	return secret.Decrypt(s.SecretKey, enc)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"openaction/internal/pipeline"
+	"openaction/internal/webhook"
+)
+
+const (
+	// webhookSecretName is the project secret deliveries are verified with.
+	webhookSecretName = "WEBHOOK_SECRET"
+	maxWebhookBody    = 25 << 20
+)
+
+// handleWebhook starts a pipeline from the project's stored spec for a push,
+// tag or pull request delivered by GitHub, Gitea or GitLab. Deliveries are
+// verified with the project's WEBHOOK_SECRET, and a redelivery with a known
+// delivery ID is acknowledged without starting another pipeline.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	providerName := chiURLParam(r, "provider")
+	provider, err := webhook.Lookup(providerName)
+	if err != nil {
+		http.Error(w, "unknown provider", http.StatusNotFound)
+		return
+	}
+	var projectID string
+	// Projects are looked up by id only: names are not unique, and a name
+	// could pick another project's secret and pipeline.
+	err = s.DB.QueryRowContext(r.Context(),
+		"SELECT id FROM projects WHERE id = ?", chiURLParam(r, "project")).Scan(&projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "project not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	secretValue, err := s.projectSecret(r.Context(), projectID, webhookSecretName)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "webhook secret not configured", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := provider.Verify(r, body, secretValue); err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	event, err := provider.Parse(r, body)
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case event.Ignore != "":
+	case event.Commit == "" || event.Ref == "":
+		event.Ignore = "no commit"
+	case webhook.Skipped(event.Message):
+		event.Ignore = "skip marker in commit message"
+	}
+
+	deliveryID := provider.DeliveryID(r)
+	recordID := randomID()
+	if deliveryID != "" {
+		res, err := s.DB.ExecContext(r.Context(), `
+      INSERT OR IGNORE INTO webhook_deliveries(id,provider,delivery_id,project_id,kind,ref,commit_hash,status,reason,created_at)
+      VALUES(?,?,?,?,?,?,?,?,?,?)`,
+			recordID, providerName, deliveryID, projectID, event.Kind, event.Ref, event.Commit, "received", event.Ignore,
+			time.Now().Unix())
+		if err != nil {
+			http.Error(w, "insert failed", http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			var status, pipelineID string
+			_ = s.DB.QueryRowContext(r.Context(),
+				"SELECT status, pipeline_id FROM webhook_deliveries WHERE provider = ? AND delivery_id = ?",
+				providerName, deliveryID).Scan(&status, &pipelineID)
+			writeJSON(w, http.StatusOK, map[string]any{"status": "duplicate", "delivery_status": status, "pipeline_id": pipelineID})
+			return
+		}
+	}
+	if event.Ignore != "" {
+		s.finishDelivery(r, recordID, "ignored", "")
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ignored", "reason": event.Ignore})
+		return
+	}
+
+	id, err := pipeline.Create(r.Context(), s.DB, pipeline.Run{
+		ProjectID:   projectID,
+		CommitHash:  event.Commit,
+		Branch:      event.Ref,
+		TriggeredBy: event.Kind,
+		Unattended:  true,
+		PullRequest: event.Number,
+	})
+	if err != nil && pipeline.Permanent(err) {
+		// A redelivery would fail the same way, so the sender is told the
+		// delivery arrived and the reason is kept with it.
+		_, _ = s.DB.ExecContext(r.Context(),
+			"UPDATE webhook_deliveries SET status = 'failed', reason = ? WHERE id = ?", err.Error(), recordID)
+		writeJSON(w, http.StatusOK, map[string]any{"status": "failed", "reason": err.Error()})
+		return
+	}
+	if err != nil {
+		// Let the provider's retry try again.
+		_, _ = s.DB.ExecContext(r.Context(), "DELETE FROM webhook_deliveries WHERE id = ?", recordID)
+		writePipelineError(w, err)
+		return
+	}
+	s.finishDelivery(r, recordID, "created", id)
+	detail := id + " (" + event.Kind + " " + event.Ref
+	if event.Sender != "" {
+		detail += " by " + event.Sender
+	}
+	s.audit(r.Context(), "webhook:"+providerName, "pipelines.create", projectID, detail+")", requestIP(r))
+	response := map[string]any{"status": "created", "id": id}
+	if superseded := s.supersede(r, id); len(superseded) > 0 {
+		response["superseded"] = superseded
+	}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func (s *Server) finishDelivery(r *http.Request, recordID, status, pipelineID string) {
+	_, _ = s.DB.ExecContext(r.Context(),
+		"UPDATE webhook_deliveries SET status = ?, pipeline_id = ? WHERE id = ?", status, pipelineID, recordID)
+}
+
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 500)
+	}
+	rows, err := s.DB.QueryContext(r.Context(), `
+    SELECT provider,delivery_id,kind,ref,commit_hash,status,reason,pipeline_id,created_at
+    FROM webhook_deliveries WHERE project_id = ? ORDER BY created_at DESC, rowid DESC LIMIT ?`,
+		chiURLParam(r, "id"), limit)
+	if err != nil {
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	items := []map[string]any{}
+	for rows.Next() {
+		var provider, deliveryID, kind, ref, commit, status, reason, pipelineID string
+		var created int64
+		if err := rows.Scan(&provider, &deliveryID, &kind, &ref, &commit, &status, &reason, &pipelineID, &created); err != nil {
+			http.Error(w, "scan failed", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, map[string]any{
+			"provider":    provider,
+			"delivery_id": deliveryID,
+			"kind":        kind,
+			"ref":         ref,
+			"commit_hash": commit,
+			"status":      status,
+			"reason":      reason,
+			"pipeline_id": pipelineID,
+			"created_at":  created,
+		})
+	}
+	writeJSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRegistrationToken = errors.New("registration token is invalid, used or expired")
+	ErrRunnerCredential  = errors.New("runner credential is invalid or revoked")
+	ErrRunnerIDTaken     = errors.New("runner ID already has an active credential")
+)
+
+// RegistrationToken is a single-use secret a runner trades for its own
+// credential on first registration.
+type RegistrationToken struct {
+	ID        string `json:"id"`
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// RunnerIdentity is the runner a credential belongs to.
+type RunnerIdentity struct {
+	RunnerID     string
+	CredentialID string
+}
+
+func (s *Service) CreateRegistrationToken(ctx context.Context, description, createdBy string, ttl time.Duration) (*RegistrationToken, error) {
+	raw := "oar_" + randomString(32)
+	now := time.Now()
+	token := &RegistrationToken{
+		ID:        uuid.NewString(),
+		Token:     raw,
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	_, err := s.DB.ExecContext(ctx, `
+    INSERT INTO runner_registration_tokens(id,token_hash,description,created_by,created_at,expires_at)
+    VALUES(?,?,?,?,?,?)`,
+		token.ID, hashSecret(raw), description, createdBy, now.Unix(), token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeRegistrationToken invalidates a token that has not been used yet.
+// It reports false when there was no such unused token.
+func (s *Service) RevokeRegistrationToken(ctx context.Context, id string) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, `
+    UPDATE runner_registration_tokens SET revoked_at = ?
+    WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`, time.Now().Unix(), id)
+	if err != nil {
+		return false, err
+	}
+	affected, _ := res.RowsAffected()
+	return affected > 0, nil
+}
+
+// RedeemRegistrationToken spends a registration token on the runner and
+// returns a new credential for it. A runner ID that still has an active
+// credential cannot be claimed with a token; its credential has to be
+// revoked first. The token is only spent when the credential is issued.
+func (s *Service) RedeemRegistrationToken(ctx context.Context, token, runnerID, ip string) (string, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var active int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(1) FROM runner_credentials WHERE runner_id = ? AND revoked_at IS NULL", runnerID).
+		Scan(&active); err != nil {
+		return "", err
+	}
+	if active > 0 {
+		return "", ErrRunnerIDTaken
+	}
+	now := time.Now().Unix()
+	var tokenID string
+	err = tx.QueryRowContext(ctx, `
+    UPDATE runner_registration_tokens SET used_at = ?, runner_id = ?
+    WHERE token_hash = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?
+    RETURNING id`, now, runnerID, hashSecret(token), now).Scan(&tokenID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrRegistrationToken
+	}
+	if err != nil {
+		return "", err
+	}
+	credential := "oac_" + randomString(40)
+	if _, err := tx.ExecContext(ctx, `
+    INSERT INTO runner_credentials(id,runner_id,token_hash,created_at) VALUES(?,?,?,?)`,
+		uuid.NewString(), runnerID, hashSecret(credential), now); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	s.auditRunner(ctx, runnerID, "runners.register", tokenID, ip)
+	return credential, nil
+}
+
+// ValidateRunnerCredential resolves a runner credential to its runner.
+func (s *Service) ValidateRunnerCredential(ctx context.Context, credential string) (*RunnerIdentity, error) {
+	var id RunnerIdentity
+	err := s.DB.QueryRowContext(ctx, `
+    SELECT id,runner_id FROM runner_credentials WHERE token_hash = ? AND revoked_at IS NULL`,
+		hashSecret(credential)).Scan(&id.CredentialID, &id.RunnerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRunnerCredential
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, _ = s.DB.ExecContext(ctx, "UPDATE runner_credentials SET last_used = ? WHERE id = ?", time.Now().Unix(), id.CredentialID)
+	return &id, nil
+}
+
+// CredentialActive reports whether a credential has not been revoked.
+func (s *Service) CredentialActive(ctx context.Context, credentialID string) bool {
+	var count int
+	err := s.DB.QueryRowContext(ctx,
+		"SELECT COUNT(1) FROM runner_credentials WHERE id = ? AND revoked_at IS NULL", credentialID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// RevokeRunnerCredentials revokes every credential of the runner and returns
+// how many were active.
+func (s *Service) RevokeRunnerCredentials(ctx context.Context, runnerID string) (int64, error) {
+	res, err := s.DB.ExecContext(ctx,
+		"UPDATE runner_credentials SET revoked_at = ? WHERE runner_id = ? AND revoked_at IS NULL",
+		time.Now().Unix(), runnerID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Service) auditRunner(ctx context.Context, runnerID, action, payload, ip string) {
+	_, _ = s.DB.ExecContext(ctx, `
+    INSERT INTO audit_trail(id,actor_id,action,resource,payload,created_at,ip)
+    VALUES(?,?,?,?,?,?,?)`,
+		uuid.NewString(), "runner:"+runnerID, action, runnerID, payload, time.Now().Unix(), ip)
+}
+
+func hashSecret(raw string) string {
+	hash := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(hash[:])
+}
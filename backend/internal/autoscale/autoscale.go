@@ -0,0 +1,365 @@
+package autoscale
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"openaction/internal/auth"
+	"openaction/internal/db"
+	"openaction/internal/scheduler"
+)
+
+// tokenTTL is how long a launched runner has to redeem its registration
+// token.
+const tokenTTL = 10 * time.Minute
+
+// Provider starts and stops the runners the autoscaler asks for. Runners it
+// launches run poold in ephemeral mode, so each takes a single job.
+type Provider interface {
+	Name() string
+	// Launch starts a runner that registers as spec.RunnerID.
+	Launch(ctx context.Context, spec Spec) error
+	// Stop asks a runner it launched to shut down.
+	Stop(ctx context.Context, runnerID string) error
+	// Running reports whether a runner it launched is still up.
+	Running(runnerID string) bool
+}
+
+// Spec describes a runner to launch.
+type Spec struct {
+	RunnerID          string
+	Group             string
+	Labels            []string
+	RegistrationToken string
+}
+
+// Group is a set of runners with the same labels, scaled on the ready jobs
+// whose runs-on expression they satisfy. A job counts towards the first
+// group that can take it.
+type Group struct {
+	Name   string
+	Labels []string
+	Min    int
+	Max    int
+	// IdleTimeout is how long a runner may wait for a job before it is
+	// stopped, as long as the group stays at or above Min.
+	IdleTimeout time.Duration
+}
+
+// GroupStatus is a group as of the last scaling pass.
+type GroupStatus struct {
+	Name        string   `json:"name"`
+	Labels      []string `json:"labels"`
+	Min         int      `json:"min"`
+	Max         int      `json:"max"`
+	IdleTimeout int64    `json:"idle_timeout_seconds"`
+	Demand      int      `json:"demand"`
+	Runners     []string `json:"runners"`
+	Idle        int      `json:"idle"`
+}
+
+// Event is a scale event, also recorded in the audit trail.
+type Event struct {
+	ID        string `json:"id"`
+	Group     string `json:"group"`
+	Action    string `json:"action"`
+	RunnerID  string `json:"runner_id"`
+	Reason    string `json:"reason"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Autoscaler launches runners through its provider when jobs are waiting for
+// a group and stops the ones left idle.
+type Autoscaler struct {
+	DB        *db.DB
+	Auth      *auth.Service
+	Scheduler *scheduler.Scheduler
+	Provider  Provider
+	Groups    []Group
+	Interval  time.Duration
+
+	mu        sync.Mutex
+	instances map[string]*instance
+	demand    map[string]int
+	idle      map[string]int
+}
+
+type instance struct {
+	runnerID   string
+	group      string
+	launchedAt time.Time
+	stopping   bool
+}
+
+func (a *Autoscaler) Run(ctx context.Context) {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.scale(ctx); err != nil {
+			log.Printf("autoscale: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops every runner the autoscaler launched and waits until they
+// have exited, so they can deregister, or until ctx ends.
+func (a *Autoscaler) Shutdown(ctx context.Context) {
+	a.mu.Lock()
+	var running []*instance
+	for _, inst := range a.instances {
+		running = append(running, inst)
+	}
+	a.mu.Unlock()
+	for _, inst := range running {
+		if err := a.Provider.Stop(ctx, inst.runnerID); err != nil {
+			log.Printf("autoscale: stop %s: %v", inst.runnerID, err)
+			continue
+		}
+		a.record(ctx, inst.group, "stop", inst.runnerID, "shutdown")
+	}
+	for _, inst := range running {
+		for a.Provider.Running(inst.runnerID) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// scale makes one pass over the groups: runners that exited are forgotten,
+// groups with more ready jobs than idle runners grow up to Max, groups
+// below Min are filled, and runners idle past IdleTimeout are stopped.
+func (a *Autoscaler) scale(ctx context.Context) error {
+	ready, err := a.Scheduler.ReadyLabels(ctx)
+	if err != nil {
+		return err
+	}
+	demand := make(map[string]int)
+	for _, labels := range ready {
+		for _, g := range a.Groups {
+			if labels.Match(g.Labels) {
+				demand[g.Name]++
+				break
+			}
+		}
+	}
+	statuses, err := a.runnerStatuses(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	if a.instances == nil {
+		a.instances = make(map[string]*instance)
+	}
+	var exited []*instance
+	byGroup := make(map[string][]*instance)
+	for id, inst := range a.instances {
+		if !a.Provider.Running(id) {
+			exited = append(exited, inst)
+			delete(a.instances, id)
+			continue
+		}
+		byGroup[inst.group] = append(byGroup[inst.group], inst)
+	}
+	a.mu.Unlock()
+	for _, inst := range exited {
+		a.record(ctx, inst.group, "exit", inst.runnerID, "runner exited")
+	}
+
+	idleCounts := make(map[string]int)
+	now := time.Now()
+	for _, g := range a.Groups {
+		group := byGroup[g.Name]
+		slices.SortFunc(group, func(x, y *instance) int { return x.launchedAt.Compare(y.launchedAt) })
+		// A runner is idle until it takes its job; one that has not
+		// registered yet is expected to take one too.
+		var idle []*instance
+		for _, inst := range group {
+			if inst.stopping {
+				continue
+			}
+			if status, ok := statuses[inst.runnerID]; !ok || status == "online" {
+				idle = append(idle, inst)
+			}
+		}
+		idleCounts[g.Name] = len(idle)
+
+		launch := demand[g.Name] - len(idle)
+		launch = max(launch, g.Min-len(group))
+		launch = min(launch, g.Max-len(group))
+		for i := 0; i < launch; i++ {
+			reason := "jobs waiting"
+			if len(group)+i < g.Min {
+				reason = "below minimum"
+			}
+			a.launch(ctx, g, reason)
+		}
+		if launch > 0 || g.IdleTimeout <= 0 {
+			continue
+		}
+		count, surplus := len(group), len(idle)-demand[g.Name]
+		for _, inst := range idle {
+			if count <= g.Min || surplus <= 0 {
+				break
+			}
+			if now.Sub(inst.launchedAt) < g.IdleTimeout {
+				continue
+			}
+			if err := a.Provider.Stop(ctx, inst.runnerID); err != nil {
+				log.Printf("autoscale: stop %s: %v", inst.runnerID, err)
+				continue
+			}
+			a.mu.Lock()
+			inst.stopping = true
+			a.mu.Unlock()
+			a.record(ctx, g.Name, "stop", inst.runnerID, "idle")
+			count--
+			surplus--
+			idleCounts[g.Name]--
+		}
+	}
+
+	a.mu.Lock()
+	a.demand = demand
+	a.idle = idleCounts
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *Autoscaler) launch(ctx context.Context, g Group, reason string) {
+	runnerID := "autoscale-" + g.Name + "-" + strings.SplitN(uuid.NewString(), "-", 2)[0]
+	token, err := a.Auth.CreateRegistrationToken(ctx, "autoscale "+g.Name, "autoscaler", tokenTTL)
+	if err != nil {
+		log.Printf("autoscale: registration token for %s: %v", g.Name, err)
+		return
+	}
+	err = a.Provider.Launch(ctx, Spec{
+		RunnerID:          runnerID,
+		Group:             g.Name,
+		Labels:            g.Labels,
+		RegistrationToken: token.Token,
+	})
+	if err != nil {
+		_, _ = a.Auth.RevokeRegistrationToken(ctx, token.ID)
+		log.Printf("autoscale: launch %s: %v", runnerID, err)
+		a.record(ctx, g.Name, "launch_failed", runnerID, err.Error())
+		return
+	}
+	a.mu.Lock()
+	a.instances[runnerID] = &instance{runnerID: runnerID, group: g.Name, launchedAt: time.Now()}
+	a.mu.Unlock()
+	a.record(ctx, g.Name, "launch", runnerID, reason)
+}
+
+func (a *Autoscaler) runnerStatuses(ctx context.Context) (map[string]string, error) {
+	rows, err := a.DB.QueryContext(ctx, "SELECT id, status FROM runners WHERE COALESCE(ephemeral,0)")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	statuses := make(map[string]string)
+	for rows.Next() {
+		var id, status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		statuses[id] = status
+	}
+	return statuses, rows.Err()
+}
+
+// record stores a scale event and adds it to the audit trail as
+// autoscale.<action>.
+func (a *Autoscaler) record(ctx context.Context, group, action, runnerID, reason string) {
+	log.Printf("autoscale: %s %s %s (%s)", group, action, runnerID, reason)
+	now := time.Now().Unix()
+	if _, err := a.DB.ExecContext(ctx, `
+    INSERT INTO runner_scale_events(id,group_name,action,runner_id,reason,created_at)
+    VALUES(?,?,?,?,?,?)`, uuid.NewString(), group, action, runnerID, reason, now); err != nil {
+		log.Printf("autoscale: record event: %v", err)
+	}
+	payload, _ := json.Marshal(map[string]any{"group": group, "reason": reason, "provider": a.Provider.Name()})
+	_, _ = a.DB.ExecContext(ctx, `
+    INSERT INTO audit_trail(id,actor_id,action,resource,payload,created_at,ip)
+    VALUES(?,?,?,?,?,?,?)`,
+		uuid.NewString(), "autoscaler", "autoscale."+action, runnerID, string(payload), now, "")
+}
+
+// Status reports every group as of the last scaling pass.
+func (a *Autoscaler) Status() []GroupStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	groups := make([]GroupStatus, 0, len(a.Groups))
+	for _, g := range a.Groups {
+		status := GroupStatus{
+			Name:        g.Name,
+			Labels:      g.Labels,
+			Min:         g.Min,
+			Max:         g.Max,
+			IdleTimeout: int64(g.IdleTimeout / time.Second),
+			Demand:      a.demand[g.Name],
+			Runners:     []string{},
+			Idle:        a.idle[g.Name],
+		}
+		for id, inst := range a.instances {
+			if inst.group == g.Name {
+				status.Runners = append(status.Runners, id)
+			}
+		}
+		slices.Sort(status.Runners)
+		groups = append(groups, status)
+	}
+	return groups
+}
+
+// GroupOf returns the group of a runner the autoscaler launched, or "".
+func (a *Autoscaler) GroupOf(runnerID string) string {
+	if a == nil {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if inst := a.instances[runnerID]; inst != nil {
+		return inst.group
+	}
+	return ""
+}
+
+// Events lists the most recent scale events, newest first.
+func (a *Autoscaler) Events(ctx context.Context, limit int) ([]Event, error) {
+	rows, err := a.DB.QueryContext(ctx, `
+    SELECT id, group_name, action, runner_id, reason, created_at
+    FROM runner_scale_events ORDER BY created_at DESC, rowid DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Group, &e.Action, &e.RunnerID, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
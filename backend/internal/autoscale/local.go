@@ -0,0 +1,127 @@
+package autoscale
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Local runs poold as child processes of the control plane. Their output
+// goes to the control plane log.
+type Local struct {
+	// Command is the poold binary, looked up in PATH when it has no slash.
+	Command string
+	// Env is added to the environment of every runner, for OA_POOL_ADDR and
+	// the TLS settings. The control plane's own OA_ variables are not
+	// passed on.
+	Env []string
+
+	mu    sync.Mutex
+	procs map[string]*exec.Cmd
+}
+
+func (l *Local) Name() string {
+	return "local"
+}
+
+func (l *Local) Launch(ctx context.Context, spec Spec) error {
+	command := l.Command
+	if command == "" {
+		command = "poold"
+	}
+	cmd := exec.Command(command, "--ephemeral")
+	cmd.Env = append(runnerEnv(), l.Env...)
+	cmd.Env = append(cmd.Env,
+		"OA_POOL_ID="+spec.RunnerID,
+		"OA_POOL_NAME="+spec.RunnerID,
+		"OA_POOL_TAGS="+strings.Join(spec.Labels, ","),
+		"OA_POOL_REGISTRATION_TOKEN="+spec.RegistrationToken,
+	)
+	out, in := io.Pipe()
+	cmd.Stdout, cmd.Stderr = in, in
+	if err := cmd.Start(); err != nil {
+		in.Close()
+		return err
+	}
+	go logOutput(spec.RunnerID, out)
+
+	l.mu.Lock()
+	if l.procs == nil {
+		l.procs = make(map[string]*exec.Cmd)
+	}
+	l.procs[spec.RunnerID] = cmd
+	l.mu.Unlock()
+	go func() {
+		err := cmd.Wait()
+		in.Close()
+		if err != nil {
+			log.Printf("runner %s: exited: %v", spec.RunnerID, err)
+		}
+		l.mu.Lock()
+		delete(l.procs, spec.RunnerID)
+		l.mu.Unlock()
+	}()
+	return nil
+}
+
+// Stop sends SIGTERM, which lets poold finish its job and deregister.
+func (l *Local) Stop(ctx context.Context, runnerID string) error {
+	l.mu.Lock()
+	cmd := l.procs[runnerID]
+	l.mu.Unlock()
+	if cmd == nil {
+		return errors.New("runner is not running")
+	}
+	return cmd.Process.Signal(syscall.SIGTERM)
+}
+
+func (l *Local) Running(runnerID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.procs[runnerID] != nil
+}
+
+// maxLogLine is how much of one line of runner output is logged.
+const maxLogLine = 16 << 10
+
+// logOutput logs a runner's output line by line until it is closed. Long
+// lines are cut at maxLogLine; the rest is still read, since a runner that
+// cannot write would never exit.
+func logOutput(runnerID string, out io.Reader) {
+	reader := bufio.NewReaderSize(out, maxLogLine)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			text := strings.TrimRight(string(line), "\r\n")
+			if err == bufio.ErrBufferFull {
+				text += " [truncated]"
+			}
+			log.Printf("runner %s: %s", runnerID, text)
+		}
+		for err == bufio.ErrBufferFull {
+			_, err = reader.ReadSlice('\n')
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// runnerEnv is the control plane's environment without its OA_ settings,
+// which would otherwise reach the steps of every job.
+func runnerEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "OA_") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
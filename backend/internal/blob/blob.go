@@ -40,6 +40,45 @@ func (s *Store) WriteCompressed(relPath string, r io.Reader) (string, int64, err
 	return fullPath, n, err
 }
 
+// WriteCompressedAt writes data to relPath as a new zstd frame at offset,
+// dropping whatever followed it, and returns the file's new size. A
+// negative offset, or one past the end of the file, appends. Readers decode
+// the concatenated frames as one stream, so logs can grow while in use.
+func (s *Store) WriteCompressedAt(relPath string, offset int64, data []byte) (int64, error) {
+	fullPath := filepath.Join(s.Root, relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(fullPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 || offset > info.Size() {
+		offset = info.Size()
+	}
+	if err := file.Truncate(offset); err != nil {
+		return 0, err
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return 0, err
+	}
+	defer encoder.Close()
+
+	frame := encoder.EncodeAll(data, nil)
+	if _, err := file.WriteAt(frame, offset); err != nil {
+		return 0, err
+	}
+	return offset + int64(len(frame)), file.Sync()
+}
+
 func (s *Store) ReadDecompressed(relPath string) (io.ReadCloser, error) {
 	fullPath := filepath.Join(s.Root, relPath)
 	file, err := os.Open(fullPath)
@@ -2,12 +2,16 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"openaction/pkg/quantity"
 )
 
 type Config struct {
@@ -26,6 +30,35 @@ type Config struct {
 	AdminEmail   string        `yaml:"admin_email"`
 	AdminPass    string        `yaml:"admin_password"`
 	PoolGRPCAddr string        `yaml:"pool_grpc_addr"`
+	JobLeaseTTL  time.Duration `yaml:"job_lease_ttl"`
+	RunnerTTL    time.Duration `yaml:"runner_timeout"`
+	QueueAging   time.Duration `yaml:"queue_aging"`
+	MinFreeDisk  int           `yaml:"runner_min_free_disk"`
+	PKIEnabled   bool          `yaml:"pki_enabled"`
+	PKIHosts     []string      `yaml:"pki_hosts"`
+	PKICertTTL   time.Duration `yaml:"pki_cert_ttl"`
+
+	Autoscale AutoscaleConfig `yaml:"autoscale"`
+}
+
+// AutoscaleConfig configures the runner autoscaler. Provider names how
+// runners are launched; only "local", which runs Command as a child process,
+// is built in.
+type AutoscaleConfig struct {
+	Enabled  bool
+	Provider string
+	Interval time.Duration
+	Command  string
+	Env      []string
+	Groups   []AutoscaleGroup
+}
+
+type AutoscaleGroup struct {
+	Name        string
+	Labels      []string
+	Min         int
+	Max         int
+	IdleTimeout time.Duration
 }
 
 type fileConfig struct {
@@ -44,8 +77,32 @@ type fileConfig struct {
 	AdminEmail   string `yaml:"admin_email"`
 	AdminPass    string `yaml:"admin_password"`
 	PoolGRPCAddr string `yaml:"pool_grpc_addr"`
+	JobLeaseTTL  string `yaml:"job_lease_ttl"`
+	RunnerTTL    string `yaml:"runner_timeout"`
+	QueueAging   string `yaml:"queue_aging"`
+	MinFreeDisk  string `yaml:"runner_min_free_disk"`
+	PKIEnabled   *bool  `yaml:"pki_enabled"`
+	PKIHosts     string `yaml:"pki_hosts"`
+	PKICertTTL   string `yaml:"pki_cert_ttl"`
+	Autoscale    *struct {
+		Enabled  *bool    `yaml:"enabled"`
+		Provider string   `yaml:"provider"`
+		Interval string   `yaml:"interval"`
+		Command  string   `yaml:"command"`
+		Env      []string `yaml:"env"`
+		Groups   []struct {
+			Name        string   `yaml:"name"`
+			Labels      []string `yaml:"labels"`
+			Min         int      `yaml:"min"`
+			Max         int      `yaml:"max"`
+			IdleTimeout string   `yaml:"idle_timeout"`
+		} `yaml:"groups"`
+	} `yaml:"autoscale"`
 }
 
+// defaultIdleTimeout applies to autoscale groups that do not set one.
+const defaultIdleTimeout = 5 * time.Minute
+
 func Load() (*Config, error) {
 	cfg := &Config{
 		DataDir:      filepath.Clean("../backend/data"),
@@ -58,6 +115,17 @@ func Load() (*Config, error) {
 		AdminEmail:   "admin@openaction.local",
 		AdminPass:    "admin123",
 		PoolGRPCAddr: ":7443",
+		JobLeaseTTL:  2 * time.Minute,
+		RunnerTTL:    time.Minute,
+		QueueAging:   time.Minute,
+		MinFreeDisk:  1024,
+		PKIHosts:     []string{"localhost", "127.0.0.1"},
+		PKICertTTL:   30 * 24 * time.Hour,
+		Autoscale: AutoscaleConfig{
+			Provider: "local",
+			Interval: 10 * time.Second,
+			Command:  "poold",
+		},
 	}
 
 	if filePath := os.Getenv("OA_CONFIG"); filePath != "" {
@@ -127,6 +195,63 @@ func Load() (*Config, error) {
 	if v := os.Getenv("OA_POOL_GRPC_ADDR"); v != "" {
 		cfg.PoolGRPCAddr = v
 	}
+	if ttl := os.Getenv("OA_JOB_LEASE_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			cfg.JobLeaseTTL = parsed
+		}
+	}
+	if ttl := os.Getenv("OA_RUNNER_TIMEOUT"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			cfg.RunnerTTL = parsed
+		}
+	}
+	if v := os.Getenv("OA_QUEUE_AGING"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			cfg.QueueAging = parsed
+		}
+	}
+	if v := os.Getenv("OA_RUNNER_MIN_FREE_DISK"); v != "" {
+		value, err := quantity.ParseMemory(v)
+		if err != nil {
+			return nil, fmt.Errorf("OA_RUNNER_MIN_FREE_DISK: %w", err)
+		}
+		cfg.MinFreeDisk = value
+	}
+	if v := os.Getenv("OA_PKI"); v != "" {
+		cfg.PKIEnabled = v == "1" || v == "true"
+	}
+	if v := os.Getenv("OA_PKI_HOSTS"); v != "" {
+		cfg.PKIHosts = splitList(v)
+	}
+	if ttl := os.Getenv("OA_PKI_CERT_TTL"); ttl != "" {
+		if parsed, err := time.ParseDuration(ttl); err == nil {
+			cfg.PKICertTTL = parsed
+		}
+	}
+	if v := os.Getenv("OA_AUTOSCALE"); v != "" {
+		cfg.Autoscale.Enabled = v == "1" || v == "true"
+	}
+	if v := os.Getenv("OA_AUTOSCALE_COMMAND"); v != "" {
+		cfg.Autoscale.Command = v
+	}
+	if v := os.Getenv("OA_AUTOSCALE_INTERVAL"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			cfg.Autoscale.Interval = parsed
+		}
+	}
+	if len(cfg.Autoscale.Groups) == 0 {
+		// Without configured groups a single group of unlabelled runners
+		// is scaled, up to OA_AUTOSCALE_MAX.
+		group := AutoscaleGroup{Name: "default", Max: 2, IdleTimeout: defaultIdleTimeout}
+		if v := os.Getenv("OA_AUTOSCALE_MAX"); v != "" {
+			value, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, errors.New("OA_AUTOSCALE_MAX must be integer")
+			}
+			group.Max = value
+		}
+		cfg.Autoscale.Groups = []AutoscaleGroup{group}
+	}
 	if cfg.SecretKey == "" {
 		return nil, errors.New("OA_SECRET_KEY is required")
 	}
@@ -192,6 +317,84 @@ func applyFileConfig(cfg *Config, path string) error {
 	if fc.PoolGRPCAddr != "" {
 		cfg.PoolGRPCAddr = fc.PoolGRPCAddr
 	}
+	if fc.JobLeaseTTL != "" {
+		if parsed, err := time.ParseDuration(fc.JobLeaseTTL); err == nil {
+			cfg.JobLeaseTTL = parsed
+		}
+	}
+	if fc.RunnerTTL != "" {
+		if parsed, err := time.ParseDuration(fc.RunnerTTL); err == nil {
+			cfg.RunnerTTL = parsed
+		}
+	}
+	if fc.QueueAging != "" {
+		if parsed, err := time.ParseDuration(fc.QueueAging); err == nil {
+			cfg.QueueAging = parsed
+		}
+	}
+	if fc.MinFreeDisk != "" {
+		value, err := quantity.ParseMemory(fc.MinFreeDisk)
+		if err != nil {
+			return fmt.Errorf("runner_min_free_disk: %w", err)
+		}
+		cfg.MinFreeDisk = value
+	}
+	if fc.PKIEnabled != nil {
+		cfg.PKIEnabled = *fc.PKIEnabled
+	}
+	if fc.PKIHosts != "" {
+		cfg.PKIHosts = splitList(fc.PKIHosts)
+	}
+	if fc.PKICertTTL != "" {
+		if parsed, err := time.ParseDuration(fc.PKICertTTL); err == nil {
+			cfg.PKICertTTL = parsed
+		}
+	}
+	if as := fc.Autoscale; as != nil {
+		if as.Enabled != nil {
+			cfg.Autoscale.Enabled = *as.Enabled
+		}
+		if as.Provider != "" {
+			cfg.Autoscale.Provider = as.Provider
+		}
+		if as.Interval != "" {
+			if parsed, err := time.ParseDuration(as.Interval); err == nil {
+				cfg.Autoscale.Interval = parsed
+			}
+		}
+		if as.Command != "" {
+			cfg.Autoscale.Command = as.Command
+		}
+		cfg.Autoscale.Env = as.Env
+		for _, g := range as.Groups {
+			if g.Name == "" {
+				return errors.New("autoscale groups need a name")
+			}
+			group := AutoscaleGroup{
+				Name:        g.Name,
+				Labels:      g.Labels,
+				Min:         max(g.Min, 0),
+				Max:         max(g.Max, g.Min),
+				IdleTimeout: defaultIdleTimeout,
+			}
+			if g.IdleTimeout != "" {
+				if parsed, err := time.ParseDuration(g.IdleTimeout); err == nil {
+					group.IdleTimeout = parsed
+				}
+			}
+			cfg.Autoscale.Groups = append(cfg.Autoscale.Groups, group)
+		}
+	}
 
 	return nil
 }
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
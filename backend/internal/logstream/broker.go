@@ -0,0 +1,98 @@
+package logstream
+
+import "sync"
+
+const subscriberBuffer = 512
+
+// Event is a single log line or the end-of-step marker. Seq is the 0-based
+// index of the line in the persisted step log, which lets subscribers skip
+// lines they already replayed from storage.
+type Event struct {
+	Seq    int64
+	Line   string
+	Done   bool
+	Status string
+}
+
+// Broker fans runner log lines out to every viewer of a step. Viewers of the
+// same step share one topic, so each line is published once.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	broker *Broker
+	stepID string
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{topics: make(map[string]map[*Subscription]struct{})}
+}
+
+func (b *Broker) Subscribe(stepID string) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, broker: b, stepID: stepID}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs, ok := b.topics[stepID]
+	if !ok {
+		subs = make(map[*Subscription]struct{})
+		b.topics[stepID] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Publish delivers a line to the step's subscribers. A subscriber that has
+// fallen too far behind is dropped: its channel is closed and it is expected
+// to catch up from the persisted log.
+func (b *Broker) Publish(stepID string, seq int64, line string) {
+	b.send(stepID, Event{Seq: seq, Line: line})
+}
+
+// Finish tells subscribers that the step reached a final status and ends
+// the topic.
+func (b *Broker) Finish(stepID, status string) {
+	b.send(stepID, Event{Seq: -1, Done: true, Status: status})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.topics[stepID] {
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) send(stepID string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.topics[stepID] {
+		select {
+		case sub.ch <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.ch)
+	subs := b.topics[sub.stepID]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.topics, sub.stepID)
+	}
+}
@@ -0,0 +1,238 @@
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"openaction/internal/db"
+	"openaction/internal/spec"
+)
+
+// Pipelines with a higher priority are scheduled first.
+const (
+	MinPriority = -100
+	MaxPriority = 100
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrNoSpec          = errors.New("project has no pipeline spec")
+	ErrInvalidPriority = errors.New("priority out of range")
+)
+
+type Run struct {
+	ProjectID   string
+	CommitHash  string
+	Branch      string
+	TriggeredBy string
+	RawSpec     string
+	Priority    int
+	// Inputs are checked against the inputs the spec declares and handed to
+	// the steps as OA_INPUT_<NAME> variables and ${{ inputs.<name> }}.
+	Inputs map[string]string
+	// Unattended runs are started by webhooks, polling and schedules rather
+	// than by someone who could fill in a form, so required inputs nobody
+	// gave get their defaults instead of failing the run.
+	Unattended bool
+	// PullRequest is the number of the pull request the run was started
+	// for, if any. Its runs are grouped apart from the branch's own.
+	PullRequest int
+}
+
+// Create parses the run's spec, falling back to the spec stored on the
+// project, and writes the pipeline together with its jobs, job graph and
+// steps. Spec problems are returned as a spec.ErrorList, input problems as
+// spec.InputErrors. Without any spec a run started by hand is recorded as a
+// queued pipeline with no jobs, as before specs existed; unattended runs
+// fail with ErrNoSpec.
+func Create(ctx context.Context, database *db.DB, run Run) (string, error) {
+	if run.Priority < MinPriority || run.Priority > MaxPriority {
+		return "", ErrInvalidPriority
+	}
+	rawSpec := run.RawSpec
+	if rawSpec == "" {
+		var stored sql.NullString
+		err := database.QueryRowContext(ctx, "SELECT pipeline_spec FROM projects WHERE id = ?", run.ProjectID).Scan(&stored)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrProjectNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+		rawSpec = stored.String
+	}
+	parsed := &spec.Pipeline{}
+	var err error
+	if strings.TrimSpace(rawSpec) != "" {
+		if parsed, err = spec.Parse([]byte(rawSpec)); err != nil {
+			return "", err
+		}
+	} else if run.Unattended {
+		return "", ErrNoSpec
+	}
+	if run.Inputs, err = parsed.ResolveInputs(run.Inputs, !run.Unattended); err != nil {
+		return "", err
+	}
+	if err := parsed.Expand(run.Inputs); err != nil {
+		return "", err
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var projectName string
+	err = tx.QueryRowContext(ctx, "SELECT name FROM projects WHERE id = ?", run.ProjectID).Scan(&projectName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrProjectNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	id := uuid.NewString()
+	now := time.Now().Unix()
+	group, cancelInProgress := concurrencyGroup(parsed, projectName, run)
+	if _, err := tx.ExecContext(ctx, `
+    INSERT INTO pipelines(id,project_id,status,commit_hash,branch,triggered_by,spec,created_at,priority,
+                          concurrency_group,cancel_in_progress,inputs,pull_request,seq)
+    VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,(SELECT COALESCE(MAX(seq),0)+1 FROM pipelines))`,
+		id, run.ProjectID, "queued", run.CommitHash, run.Branch, run.TriggeredBy, rawSpec, now, run.Priority,
+		group, cancelInProgress, encodeEnv(run.Inputs), run.PullRequest); err != nil {
+		return "", err
+	}
+	if err := insertJobs(ctx, tx, id, parsed, nil); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Permanent reports whether Create failed because of the project or its
+// spec rather than something a retry could fix.
+func Permanent(err error) bool {
+	var specErrs spec.ErrorList
+	var inputErrs spec.InputErrors
+	return errors.Is(err, ErrProjectNotFound) || errors.Is(err, ErrNoSpec) ||
+		errors.As(err, &specErrs) || errors.As(err, &inputErrs)
+}
+
+// concurrencyGroup evaluates the spec's concurrency group for the run.
+func concurrencyGroup(parsed *spec.Pipeline, projectName string, run Run) (string, bool) {
+	if parsed.Concurrency == nil {
+		return "", false
+	}
+	vars := map[string]string{
+		"project":      projectName,
+		"project_id":   run.ProjectID,
+		"branch":       groupBranch(run),
+		"commit":       run.CommitHash,
+		"triggered_by": run.TriggeredBy,
+	}
+	for name, value := range run.Inputs {
+		vars["inputs."+name] = value
+	}
+	return parsed.Concurrency.Key(vars), parsed.Concurrency.CancelInProgress
+}
+
+// groupBranch is the branch a run is grouped under. A pull request's head
+// branch can have the same name as a branch of the project, in a fork or
+// not, so its runs go under pull/<number>/<branch> instead.
+func groupBranch(run Run) string {
+	if run.PullRequest > 0 {
+		return fmt.Sprintf("pull/%d/%s", run.PullRequest, run.Branch)
+	}
+	return run.Branch
+}
+
+// insertJobs writes the spec's jobs, needs and steps. Jobs named in reuse
+// are copied as finished from the given job of an earlier attempt, together
+// with their step results and logs.
+func insertJobs(ctx context.Context, tx *sql.Tx, pipelineID string, parsed *spec.Pipeline, reuse map[string]string) error {
+	jobIDs := make(map[string]string, len(parsed.Jobs))
+	stepPosition := 0
+	for position, job := range parsed.Order() {
+		jobID := uuid.NewString()
+		jobIDs[job.Name] = jobID
+		var resources spec.Resources
+		if job.Resources != nil {
+			resources = *job.Resources
+		}
+		if previous, ok := reuse[job.Name]; ok {
+			if err := copyJob(ctx, tx, pipelineID, jobID, previous, position); err != nil {
+				return err
+			}
+		} else if _, err := tx.ExecContext(ctx, `
+      INSERT INTO pipeline_jobs(id,pipeline_id,name,status,position,env_json,working_directory,timeout_seconds,runs_on,
+                                resource_class,cpu_millis,memory_mb,checkout)
+      VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+			jobID, pipelineID, job.Name, "queued", position, encodeEnv(job.Env), job.WorkingDirectory,
+			int64(job.Timeout.Seconds()), job.RunsOn.Encode(), resources.Class, resources.CPUMillis, resources.MemoryMB,
+			job.Checkout.Encode()); err != nil {
+			return err
+		}
+		for _, need := range job.Needs {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO pipeline_job_needs(id,job_id,needs_job_id) VALUES(?,?,?)",
+				uuid.NewString(), jobID, jobIDs[need]); err != nil {
+				return err
+			}
+		}
+		for _, step := range job.Steps {
+			if previous, ok := reuse[job.Name]; ok {
+				if _, err := tx.ExecContext(ctx, `
+          INSERT INTO pipeline_steps(id,pipeline_id,job_id,name,status,position,command,env_json,working_directory,
+                                     timeout_seconds,started_at,finished_at,log_path,log_lines)
+          SELECT ?,?,?,name,status,?,command,env_json,working_directory,timeout_seconds,started_at,finished_at,log_path,log_lines
+          FROM pipeline_steps WHERE job_id = ? AND name = ?`,
+					uuid.NewString(), pipelineID, jobID, stepPosition, previous, step.Name); err != nil {
+					return err
+				}
+				stepPosition++
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `
+        INSERT INTO pipeline_steps(id,pipeline_id,job_id,name,status,position,command,env_json,working_directory,timeout_seconds)
+        VALUES(?,?,?,?,?,?,?,?,?,?)`,
+				uuid.NewString(), pipelineID, jobID, step.Name, "pending", stepPosition, step.Run,
+				encodeEnv(step.Env), step.WorkingDirectory, int64(step.Timeout.Seconds())); err != nil {
+				return err
+			}
+			stepPosition++
+		}
+	}
+	return nil
+}
+
+func copyJob(ctx context.Context, tx *sql.Tx, pipelineID, jobID, previous string, position int) error {
+	_, err := tx.ExecContext(ctx, `
+    INSERT INTO pipeline_jobs(id,pipeline_id,name,status,position,env_json,working_directory,timeout_seconds,
+                              runs_on,resource_class,cpu_millis,memory_mb,checkout,started_at,finished_at,reused_from)
+    SELECT ?,?,name,status,?,env_json,working_directory,timeout_seconds,
+           runs_on,resource_class,cpu_millis,memory_mb,checkout,started_at,finished_at,id
+    FROM pipeline_jobs WHERE id = ?`,
+		jobID, pipelineID, position, previous)
+	return err
+}
+
+func encodeEnv(env map[string]string) string {
+	if len(env) == 0 {
+		return "{}"
+	}
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return "{}"
+	}
+	return string(raw)
+}
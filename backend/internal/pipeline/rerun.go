@@ -0,0 +1,145 @@
+package pipeline
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"openaction/internal/db"
+	"openaction/internal/spec"
+)
+
+const (
+	RerunAll        = "all"
+	RerunFailedOnly = "failed-only"
+)
+
+var (
+	ErrPipelineNotFound = errors.New("pipeline not found")
+	ErrPipelineActive   = errors.New("pipeline is still running")
+	ErrNothingToRerun   = errors.New("pipeline has no failed jobs")
+	ErrInvalidMode      = errors.New("invalid rerun mode")
+)
+
+type Attempt struct {
+	ID      string `json:"id"`
+	Attempt int    `json:"attempt"`
+}
+
+// Rerun starts a new attempt of a finished pipeline from the spec it ran
+// with. In failed-only mode jobs that succeeded are carried over from the
+// rerun pipeline instead of running again.
+func Rerun(ctx context.Context, database *db.DB, pipelineID, mode, triggeredBy string) (*Attempt, error) {
+	if mode == "" {
+		mode = RerunAll
+	}
+	if mode != RerunAll && mode != RerunFailedOnly {
+		return nil, ErrInvalidMode
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var projectID, status, commit, branch, rootID, inputs string
+	var rawSpec sql.NullString
+	var priority, pullRequest int
+	err = tx.QueryRowContext(ctx, `
+    SELECT project_id,status,commit_hash,branch,spec,COALESCE(root_id,id),COALESCE(priority,0),COALESCE(inputs,''),pull_request
+    FROM pipelines WHERE id = ?`, pipelineID).
+		Scan(&projectID, &status, &commit, &branch, &rawSpec, &rootID, &priority, &inputs, &pullRequest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPipelineNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status == "queued" || status == "running" {
+		return nil, ErrPipelineActive
+	}
+	if strings.TrimSpace(rawSpec.String) == "" {
+		return nil, ErrNoSpec
+	}
+	parsed, err := spec.Parse([]byte(rawSpec.String))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if inputs != "" {
+		if err := json.Unmarshal([]byte(inputs), &values); err != nil {
+			return nil, err
+		}
+	}
+	if err := parsed.Expand(values); err != nil {
+		return nil, err
+	}
+
+	var reuse map[string]string
+	if mode == RerunFailedOnly {
+		reuse, err = succeededJobs(ctx, tx, pipelineID)
+		if err != nil {
+			return nil, err
+		}
+		if len(reuse) >= len(parsed.Jobs) {
+			return nil, ErrNothingToRerun
+		}
+	}
+
+	var attempt int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(attempt), 1) + 1 FROM pipelines WHERE COALESCE(root_id,id) = ?", rootID).
+		Scan(&attempt); err != nil {
+		return nil, err
+	}
+
+	var projectName string
+	if err := tx.QueryRowContext(ctx, "SELECT name FROM projects WHERE id = ?", projectID).Scan(&projectName); err != nil {
+		return nil, err
+	}
+	group, cancelInProgress := concurrencyGroup(parsed, projectName, Run{
+		ProjectID: projectID, CommitHash: commit, Branch: branch, TriggeredBy: triggeredBy, Inputs: values,
+		PullRequest: pullRequest,
+	})
+
+	id := uuid.NewString()
+	if _, err := tx.ExecContext(ctx, `
+    INSERT INTO pipelines(id,project_id,status,commit_hash,branch,triggered_by,spec,created_at,attempt,rerun_of,root_id,priority,
+                          concurrency_group,cancel_in_progress,inputs,pull_request,seq)
+    VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,(SELECT COALESCE(MAX(seq),0)+1 FROM pipelines))`,
+		id, projectID, "queued", commit, branch, triggeredBy, rawSpec.String, time.Now().Unix(),
+		attempt, pipelineID, rootID, priority, group, cancelInProgress, inputs, pullRequest); err != nil {
+		return nil, err
+	}
+	if err := insertJobs(ctx, tx, id, parsed, reuse); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &Attempt{ID: id, Attempt: attempt}, nil
+}
+
+func succeededJobs(ctx context.Context, tx *sql.Tx, pipelineID string) (map[string]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT name,id FROM pipeline_jobs WHERE pipeline_id = ? AND status = 'success'", pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	jobs := make(map[string]string)
+	for rows.Next() {
+		var name, id string
+		if err := rows.Scan(&name, &id); err != nil {
+			return nil, err
+		}
+		jobs[name] = id
+	}
+	return jobs, rows.Err()
+}
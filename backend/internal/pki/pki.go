@@ -0,0 +1,316 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"openaction/internal/db"
+)
+
+const (
+	caLifetime     = 10 * 365 * 24 * time.Hour
+	serverLifetime = 90 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidCSR = errors.New("certificate request is invalid")
+	ErrRevoked    = errors.New("certificate has been revoked")
+)
+
+// Authority is a certificate authority kept in the data directory. It signs
+// runner client certificates, issues the gRPC server certificate and tracks
+// which of the certificates it issued were revoked.
+type Authority struct {
+	DB *db.DB
+	// CertTTL is the lifetime of runner certificates.
+	CertTTL time.Duration
+	// Hosts are the names and addresses the server certificate is valid for.
+	Hosts []string
+
+	cert  *x509.Certificate
+	key   crypto.Signer
+	caPEM []byte
+
+	mu      sync.Mutex
+	revoked map[string]bool
+	server  *tls.Certificate
+}
+
+// Load reads the CA from dir, creating it on first start.
+func Load(ctx context.Context, database *db.DB, dir string) (*Authority, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	certPath := filepath.Join(dir, "ca.crt")
+	keyPath := filepath.Join(dir, "ca.key")
+	if _, err := os.Stat(certPath); errors.Is(err, os.ErrNotExist) {
+		if err := createCA(certPath, keyPath); err != nil {
+			return nil, fmt.Errorf("create ca: %w", err)
+		}
+	}
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("ca key cannot sign")
+	}
+	a := &Authority{
+		DB:      database,
+		cert:    cert,
+		key:     key,
+		caPEM:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		revoked: make(map[string]bool),
+	}
+	if err := a.loadRevoked(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func createCA(certPath, keyPath string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "OpenAction runner CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caLifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+// Bundle returns the CA certificate in PEM form.
+func (a *Authority) Bundle() []byte {
+	return a.caPEM
+}
+
+// Pool returns a cert pool holding the CA.
+func (a *Authority) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.cert)
+	return pool
+}
+
+// Issued reports whether the certificate was signed by this CA.
+func (a *Authority) Issued(cert *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(a.cert) == nil
+}
+
+// SignCSR issues a client certificate for the runner from a PEM or DER
+// encoded certificate request. The subject is always the runner ID, whatever
+// the request asked for.
+func (a *Authority) SignCSR(ctx context.Context, runnerID string, csr []byte) ([]byte, error) {
+	if block, _ := pem.Decode(csr); block != nil {
+		csr = block.Bytes
+	}
+	req, err := x509.ParseCertificateRequest(csr)
+	if err != nil || req.CheckSignature() != nil {
+		return nil, ErrInvalidCSR
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: runnerID, Organization: []string{"openaction-runner"}},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(a.certTTL()),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, req.PublicKey, a.key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.DB.ExecContext(ctx, `
+    INSERT INTO runner_certificates(serial,runner_id,issued_at,expires_at) VALUES(?,?,?,?)`,
+		serial.Text(16), runnerID, now.Unix(), template.NotAfter.Unix()); err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// RevokeRunner revokes every unexpired certificate issued to the runner and
+// returns how many there were.
+func (a *Authority) RevokeRunner(ctx context.Context, runnerID string) (int, error) {
+	now := time.Now().Unix()
+	rows, err := a.DB.QueryContext(ctx, `
+    SELECT serial FROM runner_certificates
+    WHERE runner_id = ? AND revoked_at IS NULL AND expires_at > ?`, runnerID, now)
+	if err != nil {
+		return 0, err
+	}
+	var serials []string
+	for rows.Next() {
+		var serial string
+		if err := rows.Scan(&serial); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		serials = append(serials, serial)
+	}
+	rows.Close()
+
+	for _, serial := range serials {
+		if _, err := a.DB.ExecContext(ctx,
+			"UPDATE runner_certificates SET revoked_at = ? WHERE serial = ?", now, serial); err != nil {
+			return 0, err
+		}
+	}
+	a.mu.Lock()
+	for _, serial := range serials {
+		a.revoked[serial] = true
+	}
+	a.mu.Unlock()
+	return len(serials), nil
+}
+
+// Revoked reports whether a certificate issued by this CA was revoked.
+func (a *Authority) Revoked(cert *x509.Certificate) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.revoked[cert.SerialNumber.Text(16)]
+}
+
+func (a *Authority) loadRevoked(ctx context.Context) error {
+	rows, err := a.DB.QueryContext(ctx, `
+    SELECT serial FROM runner_certificates WHERE revoked_at IS NOT NULL AND expires_at > ?`, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var serial string
+		if err := rows.Scan(&serial); err != nil {
+			return err
+		}
+		a.revoked[serial] = true
+	}
+	return rows.Err()
+}
+
+// VerifyPeerCertificate refuses client certificates this CA revoked. It is
+// meant for tls.Config and runs after the chain itself was verified.
+func (a *Authority) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		if len(chain) == 0 {
+			continue
+		}
+		if leaf := chain[0]; a.Issued(leaf) && a.Revoked(leaf) {
+			return ErrRevoked
+		}
+	}
+	return nil
+}
+
+// GetCertificate serves a server certificate signed by the CA, issuing a new
+// one when the current certificate has less than a third of its life left.
+func (a *Authority) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.server != nil && !dueForRenewal(a.server.Leaf) {
+		return a.server, nil
+	}
+	cert, err := a.issueServer()
+	if err != nil {
+		return nil, err
+	}
+	a.server = cert
+	return cert, nil
+}
+
+func (a *Authority) issueServer() (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "openaction control plane"},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(serverLifetime),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range a.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der, a.cert.Raw}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+func (a *Authority) certTTL() time.Duration {
+	if a.CertTTL <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return a.CertTTL
+}
+
+func dueForRenewal(cert *x509.Certificate) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return time.Until(cert.NotAfter) < lifetime/3
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
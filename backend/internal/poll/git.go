@@ -0,0 +1,94 @@
+package poll
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+// lsRemoteTimeout bounds a single git ls-remote.
+const lsRemoteTimeout = 30 * time.Second
+
+// lsRemote lists the branch and tag heads of a repository by ref name. An
+// annotated tag maps to the commit it points at.
+func lsRemote(ctx context.Context, repoURL, username, token string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, lsRemoteTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", "--tags", "--", repoURL)
+	cmd.Env = credentialEnv(repoURL, username, token)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git ls-remote: %s", msg)
+		}
+		return nil, fmt.Errorf("git ls-remote: %w", err)
+	}
+	refs := map[string]string{}
+	peeled := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		hash, ref, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		if tag, ok := strings.CutSuffix(ref, "^{}"); ok {
+			peeled[tag] = hash
+			continue
+		}
+		refs[ref] = hash
+	}
+	for ref, hash := range peeled {
+		refs[ref] = hash
+	}
+	return refs, scanner.Err()
+}
+
+// credentialEnv hands the token to git as a Basic auth header for the
+// repository's host, as runners do for the checkout.
+func credentialEnv(repoURL, username, token string) []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if token == "" {
+		return env
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return env
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+	return append(env,
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http."+u.Scheme+"://"+u.Host+"/.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+	)
+}
+
+// Match reports whether name matches one of the glob patterns, such as
+// "main" or "release/*".
+func Match(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPatterns checks that each pattern is a well-formed glob.
+func ValidPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package poll
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"openaction/internal/db"
+	"openaction/internal/pipeline"
+	"openaction/internal/scheduler"
+	"openaction/internal/secret"
+	"openaction/internal/webhook"
+)
+
+const (
+	// DefaultInterval is how often a project is polled when its poll does not
+	// say.
+	DefaultInterval = time.Minute
+	// MinInterval keeps polls from hammering a git host.
+	MinInterval = 15 * time.Second
+	// MaxBackoff caps the wait after repeated failures, unless the interval
+	// itself is longer.
+	MaxBackoff = time.Hour
+	// defaultCredentials is the secret used when the poll does not name one.
+	defaultCredentials = "GIT_TOKEN"
+)
+
+// Poller runs git ls-remote against the repositories of projects that poll
+// and starts pipelines for the new commits of their watched branches and
+// tags.
+type Poller struct {
+	DB        *db.DB
+	Scheduler *scheduler.Scheduler
+	// Interval is how often due polls are looked for.
+	Interval time.Duration
+}
+
+func (p *Poller) Run(ctx context.Context) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.tick(ctx, time.Now()); err != nil {
+			log.Printf("poll: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type due struct {
+	projectID, repoURL   string
+	interval             time.Duration
+	branches, tags, refs string
+	credentials          string
+	failures             int
+}
+
+func (p *Poller) tick(ctx context.Context, now time.Time) error {
+	rows, err := p.DB.QueryContext(ctx, `
+    SELECT pp.project_id, COALESCE(pr.repo_url,''), pp.interval_seconds, pp.branches, pp.tags, COALESCE(pp.refs,''),
+           pp.credentials, pp.failures
+    FROM project_polls pp JOIN projects pr ON pr.id = pp.project_id
+    WHERE pp.enabled = 1 AND COALESCE(pp.next_poll_at,0) <= ?`, now.Unix())
+	if err != nil {
+		return err
+	}
+	var polls []due
+	for rows.Next() {
+		var d due
+		var seconds int64
+		if err := rows.Scan(&d.projectID, &d.repoURL, &seconds, &d.branches, &d.tags, &d.refs, &d.credentials, &d.failures); err != nil {
+			rows.Close()
+			return err
+		}
+		d.interval = time.Duration(seconds) * time.Second
+		polls = append(polls, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, d := range polls {
+		if ctx.Err() != nil {
+			return nil
+		}
+		p.poll(ctx, d)
+	}
+	return nil
+}
+
+// poll lists the project's refs and starts a pipeline for each watched ref
+// that moved since the last poll. The first poll only records the refs. A
+// ref whose pipeline could not be created for a reason a retry could fix
+// keeps its old commit, so the next poll tries again.
+func (p *Poller) poll(ctx context.Context, d due) {
+	refs, listErr := p.listRefs(ctx, d)
+	now := time.Now()
+	if listErr != nil {
+		failures := d.failures + 1
+		log.Printf("poll %s: %v", d.projectID, listErr)
+		if _, err := p.DB.ExecContext(ctx, `
+      UPDATE project_polls SET failures = ?, last_polled_at = ?, last_error = ?, next_poll_at = ?
+      WHERE project_id = ?`,
+			failures, now.Unix(), listErr.Error(), now.Add(Backoff(d.interval, failures)).Unix(), d.projectID); err != nil {
+			log.Printf("poll %s: %v", d.projectID, err)
+		}
+		return
+	}
+
+	if d.refs != "" {
+		var known map[string]string
+		if err := json.Unmarshal([]byte(d.refs), &known); err != nil {
+			log.Printf("poll %s: stored refs: %v", d.projectID, err)
+		}
+		var branches, tags []string
+		_ = json.Unmarshal([]byte(d.branches), &branches)
+		_ = json.Unmarshal([]byte(d.tags), &tags)
+		names := make([]string, 0, len(refs))
+		for ref := range refs {
+			names = append(names, ref)
+		}
+		sort.Strings(names)
+		for _, ref := range names {
+			if known != nil && known[ref] == refs[ref] {
+				continue
+			}
+			var err error
+			if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok && Match(branches, branch) {
+				err = p.start(ctx, d.projectID, webhook.Push, branch, refs[ref])
+			} else if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok && Match(tags, tag) {
+				err = p.start(ctx, d.projectID, webhook.Tag, tag, refs[ref])
+			}
+			if err == nil || pipeline.Permanent(err) {
+				continue
+			}
+			if old, ok := known[ref]; ok {
+				refs[ref] = old
+			} else {
+				delete(refs, ref)
+			}
+		}
+	}
+
+	raw, err := json.Marshal(refs)
+	if err != nil {
+		log.Printf("poll %s: %v", d.projectID, err)
+		return
+	}
+	if _, err := p.DB.ExecContext(ctx, `
+    UPDATE project_polls SET refs = ?, failures = 0, last_polled_at = ?, last_success_at = ?, last_error = '',
+      next_poll_at = ?
+    WHERE project_id = ?`,
+		string(raw), now.Unix(), now.Unix(), now.Add(jitter(d.interval)).Unix(), d.projectID); err != nil {
+		log.Printf("poll %s: %v", d.projectID, err)
+	}
+}
+
+func (p *Poller) listRefs(ctx context.Context, d due) (map[string]string, error) {
+	if d.repoURL == "" {
+		return nil, errors.New("project has no repo_url")
+	}
+	username, token, err := credentials(ctx, p.DB, p.Scheduler.SecretKey, d.projectID, d.credentials)
+	if err != nil {
+		return nil, err
+	}
+	return lsRemote(ctx, d.repoURL, username, token)
+}
+
+// BranchHead looks up the commit the branch of the project's repository
+// points at, with the credentials the project's poll uses. It returns ""
+// when the project has no repository.
+func BranchHead(ctx context.Context, database *db.DB, secretKey []byte, projectID, branch string) (string, error) {
+	var repoURL, name string
+	err := database.QueryRowContext(ctx, `
+    SELECT COALESCE(pr.repo_url,''), COALESCE(pp.credentials,'')
+    FROM projects pr LEFT JOIN project_polls pp ON pp.project_id = pr.id
+    WHERE pr.id = ?`, projectID).Scan(&repoURL, &name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", pipeline.ErrProjectNotFound
+	}
+	if err != nil || repoURL == "" {
+		return "", err
+	}
+	username, token, err := credentials(ctx, database, secretKey, projectID, name)
+	if err != nil {
+		return "", err
+	}
+	refs, err := lsRemote(ctx, repoURL, username, token)
+	if err != nil {
+		return "", err
+	}
+	commit, ok := refs["refs/heads/"+branch]
+	if !ok {
+		return "", fmt.Errorf("branch %q not found in %s", branch, repoURL)
+	}
+	return commit, nil
+}
+
+// credentials reads the named secret, default GIT_TOKEN, scoped to the
+// project. Global secrets are not used: the project chooses the repository
+// they would be sent to. A value without a colon is a token for the user
+// name "git".
+func credentials(ctx context.Context, database *db.DB, secretKey []byte, projectID, name string) (string, string, error) {
+	if name == "" {
+		name = defaultCredentials
+	}
+	var enc string
+	err := database.QueryRowContext(ctx, "SELECT value_enc FROM secrets WHERE name = ? AND scope = ?",
+		name, "project:"+projectID).Scan(&enc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	value, err := secret.Decrypt(secretKey, enc)
+	if err != nil {
+		return "", "", err
+	}
+	if user, token, ok := strings.Cut(value, ":"); ok {
+		return user, token, nil
+	}
+	return "git", value, nil
+}
+
+func (p *Poller) start(ctx context.Context, projectID, kind, ref, commit string) error {
+	id, err := pipeline.Create(ctx, p.DB, pipeline.Run{
+		ProjectID:   projectID,
+		Branch:      ref,
+		CommitHash:  commit,
+		TriggeredBy: kind,
+		Unattended:  true,
+	})
+	if err != nil {
+		log.Printf("poll %s: %s %s: %v", projectID, kind, ref, err)
+		return err
+	}
+	_, _ = p.DB.ExecContext(ctx, `
+    INSERT INTO audit_trail(id,actor_id,action,resource,payload,created_at,ip)
+    VALUES(?,?,?,?,?,?,?)`,
+		uuid.NewString(), "poll", "pipelines.create", projectID, id+" ("+kind+" "+ref+" "+commit+")", time.Now().Unix(), "")
+	if _, err := p.Scheduler.Supersede(ctx, id); err != nil {
+		log.Printf("poll %s: supersede for pipeline %s: %v", projectID, id, err)
+	}
+	return nil
+}
+
+// Backoff is the wait after the given number of consecutive failures: the
+// interval doubled for each, up to MaxBackoff.
+func Backoff(interval time.Duration, failures int) time.Duration {
+	limit := max(interval, MaxBackoff)
+	delay := interval
+	for i := 0; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return jitter(min(delay, limit))
+}
+
+// jitter adds up to a tenth of d, so that projects set up together do not
+// poll together.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d + rand.N(d/10+1)
+}
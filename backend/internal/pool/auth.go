@@ -0,0 +1,156 @@
+package pool
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"openaction/internal/auth"
+	"openaction/internal/pki"
+	"openaction/pkg/poolpb"
+)
+
+// caller is who is on the other end of an RPC: a runner holding a verified
+// client certificate, or one holding a credential issued on registration.
+type caller struct {
+	cert *x509.Certificate
+	// certRunner is the runner a certificate from the built-in CA was
+	// issued to.
+	certRunner string
+	runner     *auth.RunnerIdentity
+}
+
+type callerKey struct{}
+
+// UnaryInterceptor rejects calls from runners that have neither a client
+// certificate nor a valid credential. Register is let through so a runner
+// can trade a registration token for its credential.
+func (s *Server) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	who, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if who == nil && info.FullMethod != poolpb.PoolService_Register_FullMethodName {
+		return nil, status.Error(codes.Unauthenticated, "client certificate or runner credential required")
+	}
+	return handler(context.WithValue(ctx, callerKey{}, who), req)
+}
+
+func (s *Server) StreamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	who, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	if who == nil {
+		return status.Error(codes.Unauthenticated, "client certificate or runner credential required")
+	}
+	return handler(srv, &authedStream{
+		ServerStream: stream,
+		ctx:          context.WithValue(stream.Context(), callerKey{}, who),
+	})
+}
+
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (a *authedStream) Context() context.Context { return a.ctx }
+
+// authenticate returns nil without an error when the caller presented
+// nothing at all. A credential that does not check out is an error.
+func (s *Server) authenticate(ctx context.Context) (*caller, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			token := strings.TrimSpace(strings.TrimPrefix(value, "Bearer "))
+			if token == "" {
+				continue
+			}
+			identity, err := s.Auth.ValidateRunnerCredential(ctx, token)
+			if errors.Is(err, auth.ErrRunnerCredential) {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			if err != nil {
+				log.Printf("validate runner credential: %v", err)
+				return nil, status.Error(codes.Internal, "authentication failed")
+			}
+			return &caller{runner: identity}, nil
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			who := &caller{cert: tlsInfo.State.VerifiedChains[0][0]}
+			if s.PKI != nil && s.PKI.Issued(who.cert) {
+				if s.PKI.Revoked(who.cert) {
+					return nil, status.Error(codes.Unauthenticated, pki.ErrRevoked.Error())
+				}
+				who.certRunner = who.cert.Subject.CommonName
+			}
+			return who, nil
+		}
+	}
+	return nil, nil
+}
+
+func callerFrom(ctx context.Context) *caller {
+	who, _ := ctx.Value(callerKey{}).(*caller)
+	return who
+}
+
+// callerID is the runner the caller authenticated as, or "" for a client
+// certificate that was not issued to a particular runner.
+func callerID(ctx context.Context) string {
+	who := callerFrom(ctx)
+	switch {
+	case who == nil:
+		return ""
+	case who.runner != nil:
+		return who.runner.RunnerID
+	default:
+		return who.certRunner
+	}
+}
+
+// authorize checks that a credential-authenticated caller only acts as the
+// runner its credential was issued to.
+func authorize(ctx context.Context, poolID string) error {
+	who := callerFrom(ctx)
+	if who == nil {
+		return status.Error(codes.Unauthenticated, "client certificate or runner credential required")
+	}
+	if who.runner != nil && who.runner.RunnerID != poolID {
+		return status.Error(codes.PermissionDenied, "credential belongs to another runner")
+	}
+	if who.certRunner != "" && who.certRunner != poolID {
+		return status.Error(codes.PermissionDenied, "certificate belongs to another runner")
+	}
+	return nil
+}
+
+// stillValid reports whether what the caller authenticated with has not been
+// revoked since, for long-lived streams.
+func (s *Server) stillValid(ctx context.Context, who *caller) bool {
+	if who.runner != nil && !s.Auth.CredentialActive(ctx, who.runner.CredentialID) {
+		return false
+	}
+	if who.certRunner != "" && s.PKI.Revoked(who.cert) {
+		return false
+	}
+	return true
+}
+
+func peerAddress(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -2,34 +2,254 @@ package pool
 
 import (
 	"context"
-	"time"
+	"errors"
+	"log"
 
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"openaction/internal/auth"
+	"openaction/internal/pki"
+	"openaction/internal/scheduler"
+	"openaction/pkg/jobspec"
 	"openaction/pkg/poolpb"
 )
 
 type Server struct {
 	poolpb.UnimplementedPoolServiceServer
+	Scheduler *scheduler.Scheduler
+	Auth      *auth.Service
+	// PKI signs runner certificates; nil when the built-in CA is disabled.
+	PKI *pki.Authority
 }
 
 func (s *Server) Register(ctx context.Context, req *poolpb.RegisterRequest) (*poolpb.RegisterResponse, error) {
-	assigned := uuid.NewString()
-	if req.Info != nil && req.Info.Id != "" {
-		assigned = req.Info.Id
+	info := req.Info
+	if info == nil {
+		info = &poolpb.PoolInfo{}
+	}
+	resp := &poolpb.RegisterResponse{AssignedId: info.Id}
+	who := callerFrom(ctx)
+	switch {
+	case who != nil && who.runner != nil:
+		if resp.AssignedId == "" {
+			resp.AssignedId = who.runner.RunnerID
+		}
+		if err := authorize(ctx, resp.AssignedId); err != nil {
+			return nil, err
+		}
+	case req.RegistrationToken != "":
+		if resp.AssignedId == "" {
+			resp.AssignedId = uuid.NewString()
+		}
+		credential, err := s.Auth.RedeemRegistrationToken(ctx, req.RegistrationToken, resp.AssignedId, peerAddress(ctx))
+		if errors.Is(err, auth.ErrRegistrationToken) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if errors.Is(err, auth.ErrRunnerIDTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if err != nil {
+			log.Printf("pool %s: redeem registration token: %v", resp.AssignedId, err)
+			return nil, status.Error(codes.Internal, "register failed")
+		}
+		resp.Credential = credential
+	case who != nil && who.cert != nil:
+		if resp.AssignedId == "" {
+			resp.AssignedId = who.certRunner
+		}
+		if resp.AssignedId == "" {
+			resp.AssignedId = uuid.NewString()
+		}
+	default:
+		return nil, status.Error(codes.Unauthenticated, "registration token, client certificate or runner credential required")
+	}
+	if who != nil && who.certRunner != "" {
+		if err := authorize(ctx, resp.AssignedId); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.Csr) > 0 && s.PKI != nil {
+		cert, err := s.PKI.SignCSR(ctx, resp.AssignedId, req.Csr)
+		if errors.Is(err, pki.ErrInvalidCSR) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if err != nil {
+			log.Printf("pool %s: sign csr: %v", resp.AssignedId, err)
+			return nil, status.Error(codes.Internal, "sign certificate failed")
+		}
+		resp.Certificate = cert
+		resp.CaBundle = s.PKI.Bundle()
+	}
+	if err := s.Scheduler.RegisterRunner(ctx, runner(ctx, resp.AssignedId, info)); err != nil {
+		log.Printf("pool %s: register: %v", resp.AssignedId, err)
+		return nil, status.Error(codes.Internal, "register failed")
+	}
+	return resp, nil
+}
+
+func runner(ctx context.Context, id string, info *poolpb.PoolInfo) scheduler.Runner {
+	name := info.Name
+	if name == "" {
+		name = id
+	}
+	return scheduler.Runner{
+		ID:       id,
+		Name:     name,
+		Version:  info.Version,
+		Tags:     info.Tags,
+		Hostname: info.Hostname,
+		OS:       info.Os,
+		Arch:     info.Arch,
+		Address:  peerAddress(ctx),
+
+		Slots:     int(info.Slots),
+		CPUMillis: int(info.CpuMillis),
+		MemoryMB:  int(info.MemoryMb),
+		Ephemeral: info.Ephemeral,
 	}
-	return &poolpb.RegisterResponse{AssignedId: assigned}, nil
 }
 
 func (s *Server) Heartbeat(ctx context.Context, req *poolpb.HeartbeatRequest) (*poolpb.HeartbeatResponse, error) {
-	return &poolpb.HeartbeatResponse{Ok: true}, nil
+	resp := &poolpb.HeartbeatResponse{Ok: true}
+	if req.PoolId == "" {
+		return resp, nil
+	}
+	if err := authorize(ctx, req.PoolId); err != nil {
+		return nil, err
+	}
+	if err := s.Scheduler.Touch(ctx, req.PoolId); err != nil {
+		log.Printf("pool %s: touch: %v", req.PoolId, err)
+	}
+	if err := s.Scheduler.Renew(ctx, req.PoolId); err != nil {
+		log.Printf("pool %s: renew leases: %v", req.PoolId, err)
+	}
+	s.recordTelemetry(ctx, req)
+	cancels, err := s.Scheduler.CancelRequests(ctx, req.PoolId)
+	if err != nil {
+		log.Printf("pool %s: cancel requests: %v", req.PoolId, err)
+	}
+	for _, cancel := range cancels {
+		resp.CancelJobIds = append(resp.CancelJobIds, cancel.JobID)
+	}
+	if resp.Draining, err = s.Scheduler.Draining(ctx, req.PoolId); err != nil {
+		log.Printf("pool %s: drain state: %v", req.PoolId, err)
+	}
+	return resp, nil
+}
+
+// Deregister removes a runner that is going away for good, as an ephemeral
+// runner does after its job, and revokes what it authenticated with.
+func (s *Server) Deregister(ctx context.Context, req *poolpb.DeregisterRequest) (*poolpb.DeregisterResponse, error) {
+	if req.PoolId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing pool_id")
+	}
+	if err := authorize(ctx, req.PoolId); err != nil {
+		return nil, err
+	}
+	err := s.Scheduler.RemoveRunner(ctx, req.PoolId)
+	if errors.Is(err, scheduler.ErrRunnerNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		log.Printf("pool %s: deregister: %v", req.PoolId, err)
+		return nil, status.Error(codes.Internal, "deregister failed")
+	}
+	s.RevokeRunner(ctx, req.PoolId)
+	return &poolpb.DeregisterResponse{Ok: true}, nil
+}
+
+// RevokeRunner revokes the runner's credentials and the certificates the
+// built-in CA issued to it.
+func (s *Server) RevokeRunner(ctx context.Context, runnerID string) {
+	if _, err := s.Auth.RevokeRunnerCredentials(ctx, runnerID); err != nil {
+		log.Printf("pool %s: revoke credentials: %v", runnerID, err)
+	}
+	if s.PKI == nil {
+		return
+	}
+	if _, err := s.PKI.RevokeRunner(ctx, runnerID); err != nil {
+		log.Printf("pool %s: revoke certificates: %v", runnerID, err)
+	}
+}
+
+func (s *Server) recordTelemetry(ctx context.Context, req *poolpb.HeartbeatRequest) {
+	t := req.Telemetry
+	if t == nil {
+		return
+	}
+	err := s.Scheduler.RecordTelemetry(ctx, req.PoolId, scheduler.Telemetry{
+		RecordedAt:        req.Timestamp,
+		Load1:             t.Load1,
+		Load5:             t.Load5,
+		Load15:            t.Load15,
+		CPUs:              int(t.Cpus),
+		MemoryTotalMB:     int64(t.MemoryTotalMb),
+		MemoryAvailableMB: int64(t.MemoryAvailableMb),
+		DiskTotalMB:       int64(t.DiskTotalMb),
+		DiskFreeMB:        int64(t.DiskFreeMb),
+		RunningJobs:       int(t.RunningJobs),
+		Version:           t.Version,
+	})
+	if err != nil {
+		log.Printf("pool %s: record telemetry: %v", req.PoolId, err)
+	}
 }
 
 func (s *Server) FetchJob(ctx context.Context, req *poolpb.JobRequest) (*poolpb.JobResponse, error) {
-	return &poolpb.JobResponse{JobId: "", Payload: ""}, nil
+	if req.PoolId == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing pool_id")
+	}
+	if err := authorize(ctx, req.PoolId); err != nil {
+		return nil, err
+	}
+	job, err := s.Scheduler.Lease(ctx, req.PoolId)
+	if err != nil {
+		log.Printf("pool %s: lease job: %v", req.PoolId, err)
+		return nil, status.Error(codes.Internal, "lease failed")
+	}
+	if job == nil {
+		return &poolpb.JobResponse{}, nil
+	}
+	payload, err := jobspec.Encode(job)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "encode job failed")
+	}
+	return &poolpb.JobResponse{JobId: job.ID, Payload: payload}, nil
 }
 
 func (s *Server) ReportStep(ctx context.Context, req *poolpb.StepReport) (*poolpb.StepReportResponse, error) {
-	_ = time.Now()
+	if req.JobId == "" || req.StepName == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing job_id or step_name")
+	}
+	err := s.Scheduler.ReportStep(ctx, scheduler.Report{
+		JobID:     req.JobId,
+		LeaseID:   req.LeaseId,
+		StepName:  req.StepName,
+		Status:    req.Status,
+		Timestamp: req.Timestamp,
+		LogLine:   req.LogLine,
+		PoolID:    callerID(ctx),
+	})
+	if err != nil {
+		return nil, reportError(err)
+	}
 	return &poolpb.StepReportResponse{Ok: true}, nil
 }
+
+func reportError(err error) error {
+	switch {
+	case errors.Is(err, scheduler.ErrLeaseLost):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, scheduler.ErrNotJobRunner):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, scheduler.ErrStepNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, scheduler.ErrInvalidStatus):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		log.Printf("report step: %v", err)
+		return status.Error(codes.Internal, "report failed")
+	}
+}
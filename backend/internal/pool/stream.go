@@ -0,0 +1,286 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"openaction/internal/scheduler"
+	"openaction/pkg/jobspec"
+	"openaction/pkg/poolpb"
+)
+
+const dispatchInterval = time.Second
+
+type connection struct {
+	server  *Server
+	stream  poolpb.PoolService_ConnectServer
+	poolID  string
+	session string
+	credits atomic.Int64
+	wake    chan struct{}
+	out     chan *poolpb.ServerMessage
+	// cancelled holds the leases already told to stop on this stream.
+	cancelled map[string]bool
+	// draining is the drain state last sent to the runner.
+	draining bool
+}
+
+// Connect runs a runner session. Jobs are pushed only while the runner has
+// job credits left; step events and log chunks from the runner are applied
+// in order and acked by seq.
+func (s *Server) Connect(stream poolpb.PoolService_ConnectServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := first.GetHello()
+	if hello == nil || hello.Info == nil || hello.Info.Id == "" || hello.SessionId == "" {
+		return status.Error(codes.InvalidArgument, "stream must start with a hello carrying pool id and session id")
+	}
+	if err := authorize(stream.Context(), hello.Info.Id); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	conn := &connection{
+		server:    s,
+		stream:    stream,
+		poolID:    hello.Info.Id,
+		session:   hello.SessionId,
+		wake:      make(chan struct{}, 1),
+		out:       make(chan *poolpb.ServerMessage, 64),
+		cancelled: make(map[string]bool),
+	}
+	if err := s.Scheduler.Reconcile(ctx, conn.poolID, hello.RunningJobs); err != nil {
+		log.Printf("pool %s: reconcile jobs: %v", conn.poolID, err)
+	}
+	if err := s.Scheduler.RegisterRunner(ctx, runner(ctx, conn.poolID, hello.Info)); err != nil {
+		log.Printf("pool %s: register: %v", conn.poolID, err)
+	}
+	if err := s.Scheduler.Renew(ctx, conn.poolID); err != nil {
+		log.Printf("pool %s: renew leases: %v", conn.poolID, err)
+	}
+	lastSeq, err := s.Scheduler.AppliedSeq(ctx, conn.poolID, conn.session)
+	if err != nil {
+		return status.Error(codes.Unavailable, "load applied reports failed")
+	}
+	if err := stream.Send(&poolpb.ServerMessage{Body: &poolpb.ServerMessage_Welcome{Welcome: &poolpb.Welcome{
+		PoolId:  conn.poolID,
+		LastSeq: lastSeq,
+	}}}); err != nil {
+		return err
+	}
+	log.Printf("pool %s: stream connected", conn.poolID)
+
+	errc := make(chan error, 1)
+	go func() { errc <- conn.receive(ctx) }()
+	err = conn.dispatch(ctx, errc)
+	log.Printf("pool %s: stream closed", conn.poolID)
+	return err
+}
+
+func (c *connection) receive(ctx context.Context) error {
+	for {
+		msg, err := c.stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch body := msg.Body.(type) {
+		case *poolpb.RunnerMessage_Heartbeat:
+			if !c.server.stillValid(ctx, callerFrom(ctx)) {
+				return status.Error(codes.Unauthenticated, "runner credential or certificate revoked")
+			}
+			if err := c.server.Scheduler.Touch(ctx, c.poolID); err != nil {
+				log.Printf("pool %s: touch: %v", c.poolID, err)
+			}
+			if err := c.server.Scheduler.Renew(ctx, c.poolID); err != nil {
+				log.Printf("pool %s: renew leases: %v", c.poolID, err)
+			}
+			body.Heartbeat.PoolId = c.poolID
+			c.server.recordTelemetry(ctx, body.Heartbeat)
+		case *poolpb.RunnerMessage_Credit:
+			c.credits.Add(int64(body.Credit.Jobs))
+			c.notify()
+		case *poolpb.RunnerMessage_Step:
+			err = c.apply(ctx, msg.Seq, scheduler.Report{
+				JobID:     body.Step.JobId,
+				LeaseID:   body.Step.LeaseId,
+				StepName:  body.Step.StepName,
+				Status:    body.Step.Status,
+				Timestamp: body.Step.Timestamp,
+				PoolID:    c.poolID,
+				Session:   c.session,
+				Seq:       msg.Seq,
+			})
+		case *poolpb.RunnerMessage_Logs:
+			err = c.apply(ctx, msg.Seq, scheduler.Report{
+				JobID:    body.Logs.JobId,
+				LeaseID:  body.Logs.LeaseId,
+				StepName: body.Logs.StepName,
+				LogLine:  strings.Join(body.Logs.Lines, "\n"),
+				PoolID:   c.poolID,
+				Session:  c.session,
+				Seq:      msg.Seq,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// apply hands a report to the scheduler, which skips it when it was applied
+// before, and acks it once it is applied or never can be. A
+// report for a lease the runner no longer holds is answered with a cancel
+// so the runner stops the job. Any other failure ends the stream with the
+// report unacked, so the runner sends it and what followed it again after
+// reconnecting.
+func (c *connection) apply(ctx context.Context, seq uint64, report scheduler.Report) error {
+	err := c.server.Scheduler.ReportStep(ctx, report)
+	switch {
+	case err == nil:
+	case errors.Is(err, scheduler.ErrLeaseLost):
+		c.send(ctx, &poolpb.ServerMessage{Body: &poolpb.ServerMessage_Cancel{Cancel: &poolpb.CancelJob{
+			JobId:     report.JobID,
+			LeaseId:   report.LeaseID,
+			Reason:    "lease lost",
+			LeaseLost: true,
+		}}})
+	case errors.Is(err, scheduler.ErrNotJobRunner), errors.Is(err, scheduler.ErrStepNotFound),
+		errors.Is(err, scheduler.ErrInvalidStatus):
+		log.Printf("pool %s: report step: %v", c.poolID, err)
+	default:
+		log.Printf("pool %s: report step: %v", c.poolID, err)
+		return status.Error(codes.Unavailable, "report failed")
+	}
+	c.send(ctx, ack(seq))
+	return nil
+}
+
+func (c *connection) dispatch(ctx context.Context, errc <-chan error) error {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errc:
+			return err
+		case msg := <-c.out:
+			if err := c.stream.Send(msg); err != nil {
+				return err
+			}
+		case <-c.wake:
+			if err := c.assign(ctx); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := c.sendCancels(ctx); err != nil {
+				return err
+			}
+			if err := c.sendDrain(ctx); err != nil {
+				return err
+			}
+			if err := c.assign(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendCancels tells the runner to stop jobs whose pipeline was cancelled.
+func (c *connection) sendCancels(ctx context.Context) error {
+	requests, err := c.server.Scheduler.CancelRequests(ctx, c.poolID)
+	if err != nil {
+		log.Printf("pool %s: cancel requests: %v", c.poolID, err)
+		return nil
+	}
+	for _, req := range requests {
+		if c.cancelled[req.LeaseID] {
+			continue
+		}
+		if err := c.stream.Send(&poolpb.ServerMessage{Body: &poolpb.ServerMessage_Cancel{Cancel: &poolpb.CancelJob{
+			JobId:   req.JobID,
+			LeaseId: req.LeaseID,
+			Reason:  "pipeline cancelled",
+		}}}); err != nil {
+			return err
+		}
+		c.cancelled[req.LeaseID] = true
+	}
+	return nil
+}
+
+// sendDrain tells the runner when it was drained or resumed.
+func (c *connection) sendDrain(ctx context.Context) error {
+	draining, err := c.server.Scheduler.Draining(ctx, c.poolID)
+	if err != nil {
+		log.Printf("pool %s: drain state: %v", c.poolID, err)
+		return nil
+	}
+	if draining == c.draining {
+		return nil
+	}
+	if err := c.stream.Send(&poolpb.ServerMessage{Body: &poolpb.ServerMessage_Drain{Drain: &poolpb.Drain{
+		Draining: draining,
+	}}}); err != nil {
+		return err
+	}
+	c.draining = draining
+	return nil
+}
+
+// assign leases jobs to the runner while it has credits.
+func (c *connection) assign(ctx context.Context) error {
+	for c.credits.Load() > 0 {
+		job, err := c.server.Scheduler.Lease(ctx, c.poolID)
+		if err != nil {
+			log.Printf("pool %s: lease job: %v", c.poolID, err)
+			return nil
+		}
+		if job == nil {
+			return nil
+		}
+		payload, err := jobspec.Encode(job)
+		if err != nil {
+			return status.Error(codes.Internal, "encode job failed")
+		}
+		if err := c.stream.Send(&poolpb.ServerMessage{Body: &poolpb.ServerMessage_Job{Job: &poolpb.JobAssignment{
+			JobId:   job.ID,
+			Payload: payload,
+		}}}); err != nil {
+			return err
+		}
+		c.credits.Add(-1)
+	}
+	return nil
+}
+
+func (c *connection) send(ctx context.Context, msg *poolpb.ServerMessage) {
+	select {
+	case c.out <- msg:
+	case <-ctx.Done():
+	}
+}
+
+func (c *connection) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func ack(seq uint64) *poolpb.ServerMessage {
+	return &poolpb.ServerMessage{Body: &poolpb.ServerMessage_Ack{Ack: &poolpb.Ack{Seq: seq}}}
+}
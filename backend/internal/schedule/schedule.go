@@ -0,0 +1,205 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"openaction/internal/cron"
+	"openaction/internal/db"
+	"openaction/internal/pipeline"
+	"openaction/internal/poll"
+	"openaction/internal/scheduler"
+)
+
+// Policies for the ticks a schedule missed while the control plane was down.
+const (
+	// Skip runs the latest missed tick only if it is at most Grace late.
+	Skip = "skip"
+	// CatchUp runs once for all the missed ticks, however late.
+	CatchUp = "catch_up"
+)
+
+// Grace is how late a tick may be seen before it counts as missed.
+const Grace = 5 * time.Minute
+
+var ErrInvalidPolicy = errors.New("missed must be skip or catch_up")
+
+// Parse checks a schedule's cron expression and time zone.
+func Parse(expr, timezone string) (*cron.Schedule, *time.Location, error) {
+	sched, err := cron.Parse(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown time zone %q", timezone)
+	}
+	return sched, loc, nil
+}
+
+// NextRuns returns up to n fire times after from.
+func NextRuns(sched *cron.Schedule, loc *time.Location, from time.Time, n int) []time.Time {
+	runs := make([]time.Time, 0, n)
+	t := from.In(loc)
+	for len(runs) < n {
+		if t = sched.Next(t); t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// Runner starts the pipelines of enabled schedules when they come due.
+type Runner struct {
+	DB        *db.DB
+	Scheduler *scheduler.Scheduler
+	Interval  time.Duration
+}
+
+func (r *Runner) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.tick(ctx, time.Now()); err != nil {
+			log.Printf("schedule: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type due struct {
+	id, projectID, expr, timezone, branch, inputs, missed string
+	nextRunAt                                             int64
+}
+
+func (r *Runner) tick(ctx context.Context, now time.Time) error {
+	rows, err := r.DB.QueryContext(ctx, `
+    SELECT id,project_id,cron,timezone,branch,inputs,missed,next_run_at FROM pipeline_schedules
+    WHERE enabled = 1 AND next_run_at IS NOT NULL AND next_run_at <= ?`, now.Unix())
+	if err != nil {
+		return err
+	}
+	var schedules []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.projectID, &d.expr, &d.timezone, &d.branch, &d.inputs, &d.missed, &d.nextRunAt); err != nil {
+			rows.Close()
+			return err
+		}
+		schedules = append(schedules, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, d := range schedules {
+		if err := r.fire(ctx, d, now); err != nil {
+			log.Printf("schedule %s: %v", d.id, err)
+		}
+	}
+	return nil
+}
+
+// fire starts a pipeline for the schedule's ticks up to now, as its missed
+// policy allows, and moves it to its next tick.
+func (r *Runner) fire(ctx context.Context, d due, now time.Time) error {
+	sched, loc, err := Parse(d.expr, d.timezone)
+	if err != nil {
+		_, updateErr := r.DB.ExecContext(ctx,
+			"UPDATE pipeline_schedules SET next_run_at = NULL, last_error = ? WHERE id = ?", err.Error(), d.id)
+		return errors.Join(err, updateErr)
+	}
+	// A firing starts one run however many ticks came due: the latest tick
+	// if it is at most Grace late, or with catch_up the missed ones together.
+	// Ticks more than Grace late are not walked.
+	first := time.Unix(d.nextRunAt, 0).In(loc)
+	t := first
+	if now.Sub(t) > Grace {
+		t = sched.Next(now.Add(-Grace - time.Second).In(loc))
+	}
+	var tick time.Time
+	for ; !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		tick = t
+	}
+	if tick.IsZero() && d.missed == CatchUp {
+		tick = first
+	}
+	if now.Sub(first) > Grace {
+		log.Printf("schedule %s: missed runs since %s", d.id, first.Format(time.RFC3339))
+	}
+
+	var inputs map[string]string
+	if err := json.Unmarshal([]byte(d.inputs), &inputs); err != nil {
+		return err
+	}
+	lastPipeline, lastError := "", ""
+	if !tick.IsZero() {
+		lastPipeline, err = r.start(ctx, d, inputs, tick)
+		if err != nil {
+			lastError = err.Error()
+			log.Printf("schedule %s: run for %s: %v", d.id, tick.Format(time.RFC3339), err)
+		}
+	}
+
+	var next sql.NullInt64
+	if t := sched.Next(now.In(loc)); !t.IsZero() {
+		next = sql.NullInt64{Int64: t.Unix(), Valid: true}
+	}
+	if tick.IsZero() {
+		_, err = r.DB.ExecContext(ctx, "UPDATE pipeline_schedules SET next_run_at = ? WHERE id = ?", next, d.id)
+		return err
+	}
+	_, err = r.DB.ExecContext(ctx, `
+    UPDATE pipeline_schedules SET next_run_at = ?, last_run_at = ?, last_error = ?,
+      last_pipeline_id = CASE WHEN ? = '' THEN last_pipeline_id ELSE ? END
+    WHERE id = ?`, next, now.Unix(), lastError, lastPipeline, lastPipeline, d.id)
+	return err
+}
+
+// start creates the pipeline for the tick from the branch head as it is now.
+func (r *Runner) start(ctx context.Context, d due, inputs map[string]string, tick time.Time) (string, error) {
+	commit, err := poll.BranchHead(ctx, r.DB, r.Scheduler.SecretKey, d.projectID, d.branch)
+	if err != nil {
+		return "", err
+	}
+	id, err := pipeline.Create(ctx, r.DB, pipeline.Run{
+		ProjectID:   d.projectID,
+		Branch:      d.branch,
+		CommitHash:  commit,
+		TriggeredBy: "schedule",
+		Inputs:      inputs,
+		Unattended:  true,
+	})
+	if err != nil {
+		return "", err
+	}
+	r.audit(ctx, d, id, tick)
+	if _, err := r.Scheduler.Supersede(ctx, id); err != nil {
+		log.Printf("schedule %s: supersede for pipeline %s: %v", d.id, id, err)
+	}
+	return id, nil
+}
+
+func (r *Runner) audit(ctx context.Context, d due, pipelineID string, tick time.Time) {
+	_, _ = r.DB.ExecContext(ctx, `
+    INSERT INTO audit_trail(id,actor_id,action,resource,payload,created_at,ip)
+    VALUES(?,?,?,?,?,?,?)`,
+		uuid.NewString(), "schedule:"+d.id, "pipelines.create", d.projectID,
+		pipelineID+" (tick "+tick.Format(time.RFC3339)+")", time.Now().Unix(), "")
+}
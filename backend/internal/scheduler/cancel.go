@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrPipelineNotFound = errors.New("pipeline not found")
+	ErrPipelineFinished = errors.New("pipeline already finished")
+)
+
+type Cancellation struct {
+	Cancelled int    `json:"cancelled_jobs"`
+	Stopping  int    `json:"stopping_jobs"`
+	Status    string `json:"status"`
+}
+
+type CancelRequest struct {
+	JobID   string
+	LeaseID string
+}
+
+// CancelPipeline cancels the jobs of a pipeline that have not started and
+// flags the running ones so their runners are told to stop. The pipeline
+// becomes cancelled once those runners report back.
+func (s *Scheduler) CancelPipeline(ctx context.Context, pipelineID string) (*Cancellation, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT status FROM pipelines WHERE id = ?", pipelineID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPipelineNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != "queued" && status != "running" {
+		return nil, ErrPipelineFinished
+	}
+
+	queued, stopping, err := cancelJobs(ctx, tx, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM pipelines WHERE id = ?", pipelineID).Scan(&status); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, jobID := range queued {
+		s.finishStepStreams(ctx, jobID)
+	}
+	return &Cancellation{Cancelled: len(queued), Stopping: stopping, Status: status}, nil
+}
+
+// cancelJobs cancels the pipeline's queued jobs, flags its running ones to
+// be stopped and settles it. It returns the cancelled jobs and the number
+// being stopped.
+func cancelJobs(ctx context.Context, tx *sql.Tx, pipelineID string) ([]string, int, error) {
+	queued, err := jobIDs(ctx, tx, "SELECT id FROM pipeline_jobs WHERE pipeline_id = ? AND status = 'queued'", pipelineID)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_jobs SET status = 'cancelled', finished_at = ?
+    WHERE pipeline_id = ? AND status = 'queued'`, now, pipelineID); err != nil {
+		return nil, 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_steps SET status = 'cancelled', finished_at = ?
+    WHERE status = 'pending' AND job_id IN (
+      SELECT id FROM pipeline_jobs WHERE pipeline_id = ? AND status = 'cancelled')`, now, pipelineID); err != nil {
+		return nil, 0, err
+	}
+	res, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_jobs SET cancel_requested_at = COALESCE(cancel_requested_at, ?)
+    WHERE pipeline_id = ? AND status = 'running'`, now, pipelineID)
+	if err != nil {
+		return nil, 0, err
+	}
+	stopping, _ := res.RowsAffected()
+	if err := settle(ctx, tx, pipelineID); err != nil {
+		return nil, 0, err
+	}
+	return queued, int(stopping), nil
+}
+
+// CancelRequests lists the running jobs of a pool that were asked to stop.
+func (s *Scheduler) CancelRequests(ctx context.Context, poolID string) ([]CancelRequest, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT id,lease_id FROM pipeline_jobs
+    WHERE runner_id = ? AND status = 'running' AND cancel_requested_at IS NOT NULL`, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var requests []CancelRequest
+	for rows.Next() {
+		var req CancelRequest
+		if err := rows.Scan(&req.JobID, &req.LeaseID); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+func jobIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
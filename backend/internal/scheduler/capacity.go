@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// capacity is an amount of runner resources. A CPU or memory amount of zero
+// in a runner's total means it did not advertise that resource and is not
+// limited by it.
+type capacity struct {
+	slots     int
+	cpuMillis int
+	memoryMB  int
+}
+
+// fits reports whether a job asking for cpu and memory can start on a runner
+// with this total capacity that already has used in use.
+func (c capacity) fits(used capacity, cpuMillis, memoryMB int) bool {
+	if used.slots >= c.slots {
+		return false
+	}
+	if c.cpuMillis > 0 && used.cpuMillis+cpuMillis > c.cpuMillis {
+		return false
+	}
+	if c.memoryMB > 0 && used.memoryMB+memoryMB > c.memoryMB {
+		return false
+	}
+	return true
+}
+
+// poolCapacity returns what the pool advertised when it registered. A pool
+// that never registered is treated as a single slot.
+func poolCapacity(ctx context.Context, tx *sql.Tx, poolID string) (capacity, error) {
+	var c capacity
+	err := tx.QueryRowContext(ctx, `
+    SELECT COALESCE(slots,1), COALESCE(cpu_millis,0), COALESCE(memory_mb,0) FROM runners WHERE id = ?`, poolID).
+		Scan(&c.slots, &c.cpuMillis, &c.memoryMB)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c, err
+	}
+	c.slots = max(c.slots, 1)
+	return c, nil
+}
+
+// poolUsage sums what the jobs running on the pool asked for.
+func poolUsage(ctx context.Context, tx *sql.Tx, poolID string) (capacity, error) {
+	var c capacity
+	err := tx.QueryRowContext(ctx, `
+    SELECT COUNT(1), COALESCE(SUM(cpu_millis),0), COALESCE(SUM(memory_mb),0)
+    FROM pipeline_jobs WHERE runner_id = ? AND status = 'running'`, poolID).
+		Scan(&c.slots, &c.cpuMillis, &c.memoryMB)
+	return c, err
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"openaction/internal/secret"
+	"openaction/internal/spec"
+	"openaction/pkg/jobspec"
+)
+
+// defaultCredentials is the secret used for the checkout when the spec does
+// not name one.
+const defaultCredentials = "GIT_TOKEN"
+
+// errCheckout marks checkout problems that retrying cannot fix, such as a
+// stored checkout that does not decode or credentials that no longer
+// decrypt.
+var errCheckout = errors.New("checkout")
+
+// loadCheckout adds the checkout to a leased job. Jobs whose project has no
+// repository, whose pipeline has no commit or whose spec turns the checkout
+// off get none. The credentials come from the secret scoped to the project,
+// project:<id>; global secrets are never used, since the spec picks both
+// the secret and the host it is sent to. A value without a colon is a token
+// used with the user name "git". Problems with the job itself wrap
+// errCheckout.
+func (s *Scheduler) loadCheckout(ctx context.Context, tx *sql.Tx, job *jobspec.Job) error {
+	var raw, repoURL string
+	err := tx.QueryRowContext(ctx, `
+    SELECT COALESCE(j.checkout,''), COALESCE(pr.repo_url,'')
+    FROM pipeline_jobs j
+    JOIN pipelines p ON p.id = j.pipeline_id
+    JOIN projects pr ON pr.id = p.project_id
+    WHERE j.id = ?`, job.ID).Scan(&raw, &repoURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	checkout, err := spec.DecodeCheckout(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errCheckout, err)
+	}
+	if checkout.Disabled || repoURL == "" || job.CommitHash == "" {
+		return nil
+	}
+	job.Checkout = &jobspec.Checkout{
+		RepoURL:    repoURL,
+		Depth:      checkout.Depth,
+		Filter:     checkout.Filter,
+		Submodules: checkout.Submodules,
+	}
+
+	name := checkout.Credentials
+	if name == "" {
+		name = defaultCredentials
+	}
+	var enc string
+	err = tx.QueryRowContext(ctx, "SELECT value_enc FROM secrets WHERE name = ? AND scope = ?",
+		name, "project:"+job.ProjectID).Scan(&enc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	value, err := secret.Decrypt(s.SecretKey, enc)
+	if err != nil {
+		return fmt.Errorf("%w: secret %s: %v", errCheckout, name, err)
+	}
+	job.Checkout.Username, job.Checkout.Token = "git", value
+	if user, token, ok := strings.Cut(value, ":"); ok {
+		job.Checkout.Username, job.Checkout.Token = user, token
+	}
+	return nil
+}
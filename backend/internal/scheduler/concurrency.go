@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Supersession is what a new run did to the older runs of its concurrency
+// group.
+type Supersession struct {
+	Group      string   `json:"group"`
+	Superseded []string `json:"superseded"`
+	Stopping   int      `json:"stopping_jobs"`
+}
+
+// Supersede applies a new pipeline's concurrency group: older queued runs in
+// the group are superseded, and with cancel-in-progress so are running ones,
+// whose runners are told to stop. Superseded runs end with the superseded
+// status. It returns nil when the pipeline has no group.
+func (s *Scheduler) Supersede(ctx context.Context, pipelineID string) (*Supersession, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var group string
+	var cancelInProgress bool
+	var seq int64
+	err = tx.QueryRowContext(ctx, `
+    SELECT COALESCE(concurrency_group,''), COALESCE(cancel_in_progress,0), COALESCE(seq,0)
+    FROM pipelines WHERE id = ?`, pipelineID).Scan(&group, &cancelInProgress, &seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPipelineNotFound
+	}
+	if err != nil || group == "" {
+		return nil, err
+	}
+
+	statuses := "'queued'"
+	if cancelInProgress {
+		statuses = "'queued','running'"
+	}
+	// seq orders runs created in the same second too, so two runs never
+	// supersede each other.
+	older, err := jobIDs(ctx, tx, `
+    SELECT id FROM pipelines
+    WHERE concurrency_group = ? AND COALESCE(seq,0) < ? AND status IN (`+statuses+`)
+      AND COALESCE(superseded_by,'') = ''`,
+		group, seq)
+	if err != nil {
+		return nil, err
+	}
+	result := &Supersession{Group: group, Superseded: []string{}}
+	var cancelled []string
+	for _, id := range older {
+		if _, err := tx.ExecContext(ctx, "UPDATE pipelines SET superseded_by = ? WHERE id = ?", pipelineID, id); err != nil {
+			return nil, err
+		}
+		queued, stopping, err := cancelJobs(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		cancelled = append(cancelled, queued...)
+		result.Superseded = append(result.Superseded, id)
+		result.Stopping += stopping
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, jobID := range cancelled {
+		s.finishStepStreams(ctx, jobID)
+	}
+	return result, nil
+}
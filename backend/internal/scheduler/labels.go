@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+
+	"openaction/internal/spec"
+)
+
+// flagUnschedulable marks queued jobs that no healthy online runner can take,
+// because none satisfies their runs-on expression or none is big enough for
+// their resource request, and clears the mark once one can.
+func (s *Scheduler) flagUnschedulable(ctx context.Context) error {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT r.id, COALESCE(r.cpu_millis,0), COALESCE(r.memory_mb,0), t.tag FROM runners r
+    LEFT JOIN runner_tags t ON t.runner_id = r.id
+    WHERE r.status IN ('online','busy') AND COALESCE(r.healthy,1)`)
+	if err != nil {
+		return err
+	}
+	type runner struct {
+		tags  []string
+		total capacity
+	}
+	online := make(map[string]*runner)
+	for rows.Next() {
+		var id string
+		var total capacity
+		var tag *string
+		if err := rows.Scan(&id, &total.cpuMillis, &total.memoryMB, &tag); err != nil {
+			rows.Close()
+			return err
+		}
+		r := online[id]
+		if r == nil {
+			total.slots = 1
+			r = &runner{total: total}
+			online[id] = r
+		}
+		if tag != nil {
+			r.tags = append(r.tags, *tag)
+		}
+	}
+	rows.Close()
+
+	rows, err = s.DB.QueryContext(ctx, `
+    SELECT id, COALESCE(runs_on,''), COALESCE(cpu_millis,0), COALESCE(memory_mb,0), unschedulable
+    FROM pipeline_jobs
+    WHERE status = 'queued' AND (COALESCE(runs_on,'') != '' OR cpu_millis > 0 OR memory_mb > 0 OR unschedulable)`)
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]bool)
+	for rows.Next() {
+		var id, runsOn string
+		var cpuMillis, memoryMB int
+		var flagged bool
+		if err := rows.Scan(&id, &runsOn, &cpuMillis, &memoryMB, &flagged); err != nil {
+			rows.Close()
+			return err
+		}
+		labels, err := spec.DecodeLabels(runsOn)
+		if err != nil {
+			continue
+		}
+		satisfiable := false
+		for _, r := range online {
+			if labels.Match(r.tags) && r.total.fits(capacity{}, cpuMillis, memoryMB) {
+				satisfiable = true
+				break
+			}
+		}
+		if flagged == satisfiable {
+			changed[id] = !satisfiable
+		}
+	}
+	rows.Close()
+
+	for id, flag := range changed {
+		if _, err := s.DB.ExecContext(ctx,
+			"UPDATE pipeline_jobs SET unschedulable = ? WHERE id = ?", flag, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"openaction/internal/spec"
+)
+
+const (
+	// historyDepth is how many recent pipelines of a project are averaged
+	// to estimate how long the next one takes.
+	historyDepth = 20
+	// shareWindow is how far back a project's jobs count against its fair
+	// share.
+	shareWindow = 10 * time.Minute
+)
+
+// queueUsage counts the jobs of every project that are running or started
+// within shareWindow, for the fair-share part of queueScore. It takes the
+// start of the window.
+const queueUsage = `
+    WITH usage AS (
+      SELECT p.project_id, COUNT(1) AS jobs FROM pipeline_jobs j
+      JOIN pipelines p ON p.id = j.pipeline_id
+      WHERE j.status = 'running' OR j.started_at >= ? GROUP BY p.project_id)`
+
+// queueScore ranks queued work, highest first: the pipeline's priority, plus
+// a point for every aging interval it has waited, minus its project's recent
+// jobs divided by the project's share weight. It takes the current time and
+// the aging interval in seconds, and expects pipelines as p, projects as pr
+// and queueUsage as u.
+const queueScore = `(COALESCE(p.priority, 0)
+      + (? - COALESCE(p.created_at, p.started_at)) / ?
+      - COALESCE(u.jobs, 0) * 1.0 / MAX(COALESCE(pr.share_weight, 1), 1))`
+
+// QueuedPipeline is a pipeline waiting to start, in the order the scheduler
+// will pick it. The estimates are missing while there is no finished
+// pipeline to go by or no runner online.
+type QueuedPipeline struct {
+	ID                string  `json:"id"`
+	ProjectID         string  `json:"project_id"`
+	Priority          int     `json:"priority"`
+	Position          int     `json:"position"`
+	Score             float64 `json:"score"`
+	QueuedAt          int64   `json:"queued_at"`
+	EstimatedWait     *int64  `json:"estimated_wait_seconds"`
+	EstimatedDuration *int64  `json:"estimated_duration_seconds"`
+}
+
+// Queue lists the queued pipelines with their position and estimated wait.
+// The wait is the expected remaining time of running pipelines plus the
+// expected time of those ahead, spread over the slots of online runners.
+func (s *Scheduler) Queue(ctx context.Context) ([]QueuedPipeline, error) {
+	now := time.Now().Unix()
+	rows, err := s.DB.QueryContext(ctx, queueUsage+`
+    SELECT p.id, p.project_id, COALESCE(p.priority, 0), COALESCE(p.created_at, p.started_at), `+queueScore+`
+    FROM pipelines p
+    LEFT JOIN projects pr ON pr.id = p.project_id
+    LEFT JOIN usage u ON u.project_id = p.project_id
+    WHERE p.status = 'queued'
+    ORDER BY 5 DESC, 4`, now-int64(shareWindow/time.Second), now, s.agingSeconds())
+	if err != nil {
+		return nil, err
+	}
+	var queue []QueuedPipeline
+	for rows.Next() {
+		var q QueuedPipeline
+		if err := rows.Scan(&q.ID, &q.ProjectID, &q.Priority, &q.QueuedAt, &q.Score); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		q.Position = len(queue) + 1
+		queue = append(queue, q)
+	}
+	rows.Close()
+
+	durations, fallback, err := s.pipelineDurations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expected := func(projectID string) (int64, bool) {
+		if d, ok := durations[projectID]; ok {
+			return d, true
+		}
+		return fallback, fallback > 0
+	}
+	var slots int64
+	if err := s.DB.QueryRowContext(ctx, `
+    SELECT COALESCE(SUM(MAX(COALESCE(slots,1),1)),0) FROM runners WHERE status IN ('online','busy')`).Scan(&slots); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.QueryContext(ctx,
+		"SELECT project_id, COALESCE(started_at, ?) FROM pipelines WHERE status = 'running'", now)
+	if err != nil {
+		return nil, err
+	}
+	var backlog int64
+	known := true
+	for rows.Next() {
+		var projectID string
+		var started int64
+		if err := rows.Scan(&projectID, &started); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d, ok := expected(projectID)
+		known = known && ok
+		backlog += max(d-(now-started), 0)
+	}
+	rows.Close()
+
+	for i := range queue {
+		d, ok := expected(queue[i].ProjectID)
+		if ok {
+			queue[i].EstimatedDuration = &d
+		}
+		known = known && ok
+		if known && slots > 0 {
+			wait := backlog / slots
+			queue[i].EstimatedWait = &wait
+		}
+		backlog += d
+	}
+	return queue, nil
+}
+
+// ReadyLabels returns the runs-on expression of every ready job, nil for the
+// ones that run anywhere, so demand can be measured per label set.
+func (s *Scheduler) ReadyLabels(ctx context.Context) ([]*spec.Labels, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var labels []*spec.Labels
+	now := time.Now().Unix()
+	for offset := 0; ; offset += candidateLimit {
+		candidates, err := readyJobs(ctx, tx, now, s.agingSeconds(), offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range candidates {
+			if !candidate.invalid {
+				labels = append(labels, candidate.runsOn)
+			}
+		}
+		if len(candidates) < candidateLimit {
+			return labels, nil
+		}
+	}
+}
+
+// pipelineDurations averages the run time of each project's most recent
+// finished pipelines, and of all of those together as a fallback for
+// projects without history.
+func (s *Scheduler) pipelineDurations(ctx context.Context) (map[string]int64, int64, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT project_id, finished_at - started_at FROM (
+      SELECT project_id, started_at, finished_at,
+             ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY finished_at DESC) AS n
+      FROM pipelines
+      WHERE status IN ('success','error') AND started_at IS NOT NULL AND finished_at IS NOT NULL)
+    WHERE n <= ?`, historyDepth)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	type total struct{ sum, count int64 }
+	totals := make(map[string]*total)
+	var all total
+	for rows.Next() {
+		var projectID string
+		var d int64
+		if err := rows.Scan(&projectID, &d); err != nil {
+			return nil, 0, err
+		}
+		d = max(d, 0)
+		t := totals[projectID]
+		if t == nil {
+			t = &total{}
+			totals[projectID] = t
+		}
+		t.sum += d
+		t.count++
+		all.sum += d
+		all.count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	durations := make(map[string]int64, len(totals))
+	for projectID, t := range totals {
+		durations[projectID] = t.sum / t.count
+	}
+	var fallback int64
+	if all.count > 0 {
+		fallback = all.sum / all.count
+	}
+	return durations, fallback, nil
+}
+
+func (s *Scheduler) agingSeconds() int64 {
+	if s.Aging < time.Second {
+		return 60
+	}
+	return int64(s.Aging / time.Second)
+}
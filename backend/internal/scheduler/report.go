@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrStepNotFound  = errors.New("step not found")
+	ErrInvalidStatus = errors.New("invalid step status")
+	ErrNotJobRunner  = errors.New("job is leased to another runner")
+)
+
+type Report struct {
+	JobID     string
+	LeaseID   string
+	StepName  string
+	Status    string
+	Timestamp int64
+	LogLine   string
+	// PoolID is the runner the report came from, when it is known. It has
+	// to be the runner the job is leased to.
+	PoolID string
+	// Session and Seq identify a report sent on a runner stream. A report
+	// whose seq is not above the last one applied to the job in the same
+	// session was applied before and is skipped. Unary reports leave them
+	// empty.
+	Session string
+	Seq     uint64
+}
+
+// ReportStep applies a runner report to its step: log lines are appended to
+// the step log and status changes are recorded. Once the job has no step
+// left to run it is finished and rolled up to the pipeline. Reports must
+// carry the job's current lease.
+func (s *Scheduler) ReportStep(ctx context.Context, report Report) error {
+	switch report.Status {
+	case "", "running", "success", "error", "cancelled":
+	default:
+		return ErrInvalidStatus
+	}
+
+	// The lease is checked in the same transaction that applies the report,
+	// so a report cannot land on a job that was re-leased in between. The log
+	// is written before the commit, at the size recorded by the last report
+	// that committed, so a report whose write or commit failed is applied
+	// whole when it is sent again, without its lines being written twice.
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var leaseID, runnerID, jobStatus, session string
+	var seq uint64
+	err = tx.QueryRowContext(ctx, `
+    SELECT COALESCE(lease_id,''), COALESCE(runner_id,''), status, COALESCE(report_session,''), report_seq
+    FROM pipeline_jobs WHERE id = ?`, report.JobID).Scan(&leaseID, &runnerID, &jobStatus, &session, &seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLeaseLost
+	}
+	if err != nil {
+		return err
+	}
+	if report.Session != "" && report.Session == session && report.Seq <= seq && report.LeaseID == leaseID {
+		// Already applied. Finishing the job may still have failed after
+		// the report committed, so a final report tries that again.
+		_ = tx.Rollback()
+		if jobStatus == "running" && report.Status != "" && report.Status != "running" {
+			return s.finishIfDone(ctx, report.JobID, leaseID)
+		}
+		return nil
+	}
+	if jobStatus != "running" {
+		return ErrLeaseLost
+	}
+	if report.PoolID != "" && report.PoolID != runnerID {
+		return ErrNotJobRunner
+	}
+	if report.LeaseID == "" || report.LeaseID != leaseID {
+		return ErrLeaseLost
+	}
+
+	var stepID string
+	var logPath sql.NullString
+	err = tx.QueryRowContext(ctx,
+		"SELECT id,log_path FROM pipeline_steps WHERE job_id = ? AND name = ?", report.JobID, report.StepName).
+		Scan(&stepID, &logPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrStepNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	var first int64
+	if report.LogLine != "" {
+		if lines, first, err = s.writeLog(ctx, tx, stepID, leaseID, logPath, report.LogLine); err != nil {
+			return err
+		}
+	}
+
+	at := report.Timestamp
+	if at <= 0 {
+		at = time.Now().Unix()
+	}
+	switch report.Status {
+	case "running":
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_steps SET status = 'running', started_at = COALESCE(started_at, ?)
+      WHERE id = ? AND status IN ('pending','running')`, at, stepID); err != nil {
+			return err
+		}
+	case "success", "error", "cancelled":
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_steps SET status = ?, started_at = COALESCE(started_at, ?), finished_at = ?
+      WHERE id = ? AND status IN ('pending','running')`, report.Status, at, at, stepID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE pipeline_jobs SET lease_expires_at = ? WHERE id = ?",
+		time.Now().Add(s.leaseTTL()).Unix(), report.JobID); err != nil {
+		return err
+	}
+	if report.Session != "" {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE pipeline_jobs SET report_session = ?, report_seq = ? WHERE id = ?",
+			report.Session, report.Seq, report.JobID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// Live viewers get the lines with their position in the log, so they can
+	// line them up with what they replayed from storage.
+	for i, line := range lines {
+		s.Broker.Publish(stepID, first+int64(i), line)
+	}
+
+	if report.Status == "" || report.Status == "running" {
+		return nil
+	}
+	s.Broker.Finish(stepID, report.Status)
+	return s.finishIfDone(ctx, report.JobID, leaseID)
+}
+
+// writeLog adds text to the step log, picking the step's log path first if
+// it has none, and counts its lines. It returns the lines and the position
+// of the first one in the log.
+func (s *Scheduler) writeLog(ctx context.Context, tx *sql.Tx, stepID, leaseID string, logPath sql.NullString, text string) ([]string, int64, error) {
+	if !logPath.Valid || logPath.String == "" {
+		logPath = sql.NullString{String: s.Blob.RelativePath("logs", leaseID+"-"+stepID), Valid: true}
+		if _, err := tx.ExecContext(ctx, "UPDATE pipeline_steps SET log_path = ? WHERE id = ?", logPath.String, stepID); err != nil {
+			return nil, 0, err
+		}
+	}
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	var total int64
+	var size sql.NullInt64
+	if err := tx.QueryRowContext(ctx,
+		"UPDATE pipeline_steps SET log_lines = COALESCE(log_lines, 0) + ? WHERE id = ? RETURNING log_lines, log_size",
+		len(lines), stepID).Scan(&total, &size); err != nil {
+		return nil, 0, err
+	}
+	offset := int64(-1)
+	if size.Valid {
+		offset = size.Int64
+	}
+	written, err := s.Blob.WriteCompressedAt(logPath.String, offset, []byte(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE pipeline_steps SET log_size = ? WHERE id = ?", written, stepID); err != nil {
+		return nil, 0, err
+	}
+	return lines, total - int64(len(lines)), nil
+}
+
+// AppliedSeq is the seq of the last report applied from the runner's stream
+// session, which the runner resumes after.
+func (s *Scheduler) AppliedSeq(ctx context.Context, poolID, session string) (uint64, error) {
+	var seq uint64
+	err := s.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(report_seq), 0) FROM pipeline_jobs WHERE runner_id = ? AND report_session = ?",
+		poolID, session).Scan(&seq)
+	return seq, err
+}
+
+func (s *Scheduler) finishIfDone(ctx context.Context, jobID, leaseID string) error {
+	var open, failed, cancelled int
+	err := s.DB.QueryRowContext(ctx, `
+    SELECT
+      COALESCE(SUM(CASE WHEN status IN ('pending','running') THEN 1 ELSE 0 END), 0),
+      COALESCE(SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END), 0),
+      COALESCE(SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END), 0)
+    FROM pipeline_steps WHERE job_id = ?`, jobID).Scan(&open, &failed, &cancelled)
+	if err != nil {
+		return err
+	}
+	switch {
+	case failed > 0:
+		return s.FinishJob(ctx, leaseID, "error")
+	case cancelled > 0:
+		return s.FinishJob(ctx, leaseID, "cancelled")
+	case open == 0:
+		return s.FinishJob(ctx, leaseID, "success")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Runner is what a pool reports about itself when it registers.
+type Runner struct {
+	ID       string
+	Name     string
+	Version  string
+	Tags     []string
+	Hostname string
+	OS       string
+	Arch     string
+	Address  string
+
+	Slots     int
+	CPUMillis int
+	MemoryMB  int
+	// Ephemeral runners take one job and are removed rather than marked
+	// offline when they go away.
+	Ephemeral bool
+}
+
+// RegisterRunner records a pool in the runners table, creating it on first
+// contact. Tags reported by the pool replace the stored ones; a pool that
+// reports none keeps the tags assigned through the API.
+func (s *Scheduler) RegisterRunner(ctx context.Context, runner Runner) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `
+    INSERT INTO runners(id,name,status,version,last_seen,created_at,hostname,os,arch,address,slots,cpu_millis,memory_mb,ephemeral)
+    VALUES(?,?,'online',?,?,?,?,?,?,?,?,?,?,?)
+    ON CONFLICT(id) DO UPDATE SET
+      name = excluded.name, version = excluded.version, last_seen = excluded.last_seen,
+      hostname = excluded.hostname, os = excluded.os, arch = excluded.arch, address = excluded.address,
+      slots = excluded.slots, cpu_millis = excluded.cpu_millis, memory_mb = excluded.memory_mb,
+      ephemeral = excluded.ephemeral,
+      status = CASE WHEN runners.status IN ('online','busy','offline','draining','drained') THEN 'online' ELSE runners.status END`,
+		runner.ID, runner.Name, runner.Version, now, now,
+		runner.Hostname, runner.OS, runner.Arch, runner.Address,
+		max(runner.Slots, 1), runner.CPUMillis, runner.MemoryMB, runner.Ephemeral); err != nil {
+		return err
+	}
+	if len(runner.Tags) > 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM runner_tags WHERE runner_id = ?", runner.ID); err != nil {
+			return err
+		}
+		for _, tag := range runner.Tags {
+			if tag == "" {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT INTO runner_tags(id,runner_id,tag) VALUES(?,?,?)",
+				uuid.NewString(), runner.ID, tag); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return s.Touch(ctx, runner.ID)
+}
+
+var ErrRunnerNotFound = errors.New("runner not found")
+
+// runnerStatus is the status of a connected runner: draining while a drained
+// runner still has jobs and drained once it has none, otherwise busy once all
+// of its slots hold a running job and online before that.
+const runnerStatus = `CASE
+      WHEN COALESCE(runners.draining,0) THEN CASE WHEN EXISTS(
+        SELECT 1 FROM pipeline_jobs WHERE runner_id = runners.id AND status = 'running'
+      ) THEN 'draining' ELSE 'drained' END
+      WHEN (
+        SELECT COUNT(1) FROM pipeline_jobs WHERE runner_id = runners.id AND status = 'running'
+      ) >= MAX(COALESCE(runners.slots,1),1) THEN 'busy'
+      ELSE 'online' END`
+
+// Touch records a heartbeat from the pool and updates its status, see
+// runnerStatus. Statuses set by an operator are left alone.
+func (s *Scheduler) Touch(ctx context.Context, poolID string) error {
+	_, err := s.DB.ExecContext(ctx, `
+    UPDATE runners SET last_seen = ?, status = `+runnerStatus+`
+    WHERE id = ? AND status IN ('online','busy','offline','draining','drained')`, time.Now().Unix(), poolID)
+	return err
+}
+
+// Drain stops leasing jobs to the runner. Jobs it holds run to completion,
+// and a connected runner is told so it can exit once idle.
+func (s *Scheduler) Drain(ctx context.Context, poolID string) error {
+	return s.setDraining(ctx, poolID, true)
+}
+
+// Resume lets a drained runner take jobs again.
+func (s *Scheduler) Resume(ctx context.Context, poolID string) error {
+	return s.setDraining(ctx, poolID, false)
+}
+
+func (s *Scheduler) setDraining(ctx context.Context, poolID string, draining bool) error {
+	var requested any
+	if draining {
+		requested = time.Now().Unix()
+	}
+	res, err := s.DB.ExecContext(ctx,
+		"UPDATE runners SET draining = ?, drain_requested_at = ? WHERE id = ?", draining, requested, poolID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrRunnerNotFound
+	}
+	_, err = s.DB.ExecContext(ctx, `
+    UPDATE runners SET status = `+runnerStatus+`
+    WHERE id = ? AND status IN ('online','busy','draining','drained')`, poolID)
+	return err
+}
+
+// Draining reports whether the runner was drained.
+func (s *Scheduler) Draining(ctx context.Context, poolID string) (bool, error) {
+	var draining bool
+	err := s.DB.QueryRowContext(ctx, "SELECT COALESCE(draining,0) FROM runners WHERE id = ?", poolID).Scan(&draining)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return draining, err
+}
+
+// RemoveRunner forgets a runner, releasing the jobs it still holds. It is
+// used when an ephemeral runner deregisters.
+func (s *Scheduler) RemoveRunner(ctx context.Context, poolID string) error {
+	held, err := s.releaseJobs(ctx, poolID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM runner_tags WHERE runner_id = ?", poolID); err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM runner_telemetry WHERE runner_id = ?", poolID); err != nil {
+		return err
+	}
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM runners WHERE id = ?", poolID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrRunnerNotFound
+	}
+	log.Printf("scheduler: pool %s removed with %d jobs released", poolID, held)
+	return nil
+}
+
+// reapRunners marks pools that missed their heartbeats offline and releases
+// the jobs they held. Ephemeral pools are removed instead and RevokeRunner
+// is called for them.
+func (s *Scheduler) reapRunners(ctx context.Context) error {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT id, COALESCE(ephemeral,0) FROM runners WHERE status IN ('online','busy','draining','drained') AND last_seen < ?`,
+		time.Now().Add(-s.runnerTimeout()).Unix())
+	if err != nil {
+		return err
+	}
+	var stale, ephemeral []string
+	for rows.Next() {
+		var id string
+		var isEphemeral bool
+		if err := rows.Scan(&id, &isEphemeral); err != nil {
+			rows.Close()
+			return err
+		}
+		if isEphemeral {
+			ephemeral = append(ephemeral, id)
+		} else {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+
+	for _, poolID := range stale {
+		if _, err := s.DB.ExecContext(ctx,
+			"UPDATE runners SET status = 'offline' WHERE id = ?", poolID); err != nil {
+			return err
+		}
+		held, err := s.releaseJobs(ctx, poolID)
+		if err != nil {
+			return err
+		}
+		log.Printf("scheduler: pool %s missed its heartbeats, offline with %d jobs released", poolID, held)
+	}
+	for _, poolID := range ephemeral {
+		if err := s.RemoveRunner(ctx, poolID); err != nil && !errors.Is(err, ErrRunnerNotFound) {
+			return err
+		}
+		if s.RevokeRunner != nil {
+			s.RevokeRunner(ctx, poolID)
+		}
+	}
+	return nil
+}
+
+// releaseJobs requeues the running jobs leased to the pool and returns how
+// many there were.
+func (s *Scheduler) releaseJobs(ctx context.Context, poolID string) (int, error) {
+	jobs, err := s.DB.QueryContext(ctx,
+		"SELECT id FROM pipeline_jobs WHERE runner_id = ? AND status = 'running'", poolID)
+	if err != nil {
+		return 0, err
+	}
+	var held []string
+	for jobs.Next() {
+		var id string
+		if err := jobs.Scan(&id); err != nil {
+			jobs.Close()
+			return 0, err
+		}
+		held = append(held, id)
+	}
+	jobs.Close()
+	for _, jobID := range held {
+		if err := s.requeue(ctx, jobID); err != nil {
+			return 0, err
+		}
+	}
+	return len(held), nil
+}
+
+func (s *Scheduler) runnerTimeout() time.Duration {
+	if s.RunnerTimeout <= 0 {
+		return time.Minute
+	}
+	return s.RunnerTimeout
+}
@@ -0,0 +1,562 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"openaction/internal/blob"
+	"openaction/internal/db"
+	"openaction/internal/logstream"
+	"openaction/internal/spec"
+	"openaction/pkg/jobspec"
+)
+
+const candidateLimit = 50
+
+var ErrLeaseLost = errors.New("job lease is no longer held")
+
+type Scheduler struct {
+	DB       *db.DB
+	Blob     *blob.Store
+	Broker   *logstream.Broker
+	LeaseTTL time.Duration
+	// RunnerTimeout is how long a pool may go without a heartbeat before it
+	// is marked offline.
+	RunnerTimeout time.Duration
+	// Aging is how long a pipeline waits in the queue to gain one point of
+	// priority.
+	Aging time.Duration
+	// RevokeRunner, when set, is called for an ephemeral runner that went
+	// away without deregistering so its credentials can be revoked.
+	RevokeRunner func(ctx context.Context, runnerID string)
+	// MinFreeDiskMB is the free workspace disk below which a runner is
+	// unhealthy and gets no jobs.
+	MinFreeDiskMB int
+	// SecretKey decrypts the secrets handed to runners for the checkout.
+	SecretKey []byte
+}
+
+// Lease hands the next ready job to the calling pool. A job is ready when it
+// is queued and every job it needs has succeeded, and it is handed out only
+// if the pool's tags satisfy its runs-on expression and the pool has a free
+// slot and enough CPU and memory left for it. Jobs too big for what is left
+// are passed over for later ones that fit. Ready jobs are tried by priority,
+// age and their project's fair share, see queueScore. A pipeline does not
+// start while another run of its concurrency group is running, and a
+// draining or unhealthy pool gets nothing. An ephemeral pool is drained by
+// its first job. It returns nil when there is nothing to run.
+func (s *Scheduler) Lease(ctx context.Context, poolID string) (*jobspec.Job, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var draining, ephemeral bool
+	healthy := true
+	err = tx.QueryRowContext(ctx, `
+    SELECT COALESCE(draining,0), COALESCE(ephemeral,0), COALESCE(healthy,1) FROM runners WHERE id = ?`, poolID).
+		Scan(&draining, &ephemeral, &healthy)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if draining || !healthy {
+		return nil, tx.Commit()
+	}
+	tags, err := poolTags(ctx, tx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	total, err := poolCapacity(ctx, tx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	used, err := poolUsage(ctx, tx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	if used.slots >= total.slots {
+		return nil, tx.Commit()
+	}
+	now := time.Now()
+	for offset := 0; ; offset += candidateLimit {
+		candidates, err := readyJobs(ctx, tx, now.Unix(), s.agingSeconds(), offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, candidate := range candidates {
+			if candidate.invalid || !candidate.runsOn.Match(tags) ||
+				!total.fits(used, candidate.cpuMillis, candidate.memoryMB) {
+				continue
+			}
+			leaseID := uuid.NewString()
+			res, err := tx.ExecContext(ctx, `
+        UPDATE pipeline_jobs
+        SET status = 'running', runner_id = ?, lease_id = ?, lease_expires_at = ?, started_at = ?
+        WHERE id = ? AND status = 'queued'`,
+				poolID, leaseID, now.Add(s.leaseTTL()).Unix(), now.Unix(), candidate.id)
+			if err != nil {
+				return nil, err
+			}
+			if affected, _ := res.RowsAffected(); affected == 0 {
+				continue
+			}
+			job, err := loadJob(ctx, tx, candidate.id)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.loadCheckout(ctx, tx, job); errors.Is(err, errCheckout) {
+				// Every runner would get the same error, so the job fails
+				// instead of holding up the queue.
+				log.Printf("scheduler: job %s: %v", job.ID, err)
+				if err := failJob(ctx, tx, job.ID, job.PipelineID, err.Error()); err != nil {
+					return nil, err
+				}
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if _, err := tx.ExecContext(ctx, `
+        UPDATE pipelines SET status = 'running', started_at = ?
+        WHERE id = ? AND status = 'queued'`, now.Unix(), job.PipelineID); err != nil {
+				return nil, err
+			}
+			if ephemeral {
+				if _, err := tx.ExecContext(ctx,
+					"UPDATE runners SET draining = 1, drain_requested_at = ? WHERE id = ?", now.Unix(), poolID); err != nil {
+					return nil, err
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				return nil, err
+			}
+			if err := s.Touch(ctx, poolID); err != nil {
+				log.Printf("scheduler: touch pool %s: %v", poolID, err)
+			}
+			return job, nil
+		}
+		if len(candidates) < candidateLimit {
+			return nil, tx.Commit()
+		}
+	}
+}
+
+// failJob fails a job that cannot be handed to any runner, along with the
+// jobs that need it, and settles its pipeline.
+func failJob(ctx context.Context, tx *sql.Tx, jobID, pipelineID, reason string) error {
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_jobs
+    SET status = 'error', error = ?, finished_at = ?, runner_id = NULL, lease_id = NULL, lease_expires_at = NULL
+    WHERE id = ?`, reason, now, jobID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE pipeline_steps SET status = 'skipped' WHERE job_id = ? AND status = 'pending'", jobID); err != nil {
+		return err
+	}
+	return settle(ctx, tx, pipelineID)
+}
+
+// Renew extends every lease held by the pool.
+func (s *Scheduler) Renew(ctx context.Context, poolID string) error {
+	_, err := s.DB.ExecContext(ctx, `
+    UPDATE pipeline_jobs SET lease_expires_at = ?
+    WHERE runner_id = ? AND status = 'running'`,
+		time.Now().Add(s.leaseTTL()).Unix(), poolID)
+	return err
+}
+
+// Reconcile requeues jobs leased to the pool that it says it is not running,
+// such as an assignment that was lost when its connection dropped.
+func (s *Scheduler) Reconcile(ctx context.Context, poolID string, running []string) error {
+	rows, err := s.DB.QueryContext(ctx,
+		"SELECT id FROM pipeline_jobs WHERE runner_id = ? AND status = 'running'", poolID)
+	if err != nil {
+		return err
+	}
+	active := make(map[string]bool, len(running))
+	for _, id := range running {
+		active[id] = true
+	}
+	var orphaned []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if !active[id] {
+			orphaned = append(orphaned, id)
+		}
+	}
+	rows.Close()
+
+	for _, jobID := range orphaned {
+		if err := s.requeue(ctx, jobID); err != nil {
+			return err
+		}
+		log.Printf("scheduler: pool %s is not running job %s, released", poolID, jobID)
+	}
+	return nil
+}
+
+// FinishJob records the final status of a leased job and rolls the result
+// up to its pipeline.
+func (s *Scheduler) FinishJob(ctx context.Context, leaseID, status string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var jobID, pipelineID, poolID string
+	err = tx.QueryRowContext(ctx,
+		"SELECT id,pipeline_id,runner_id FROM pipeline_jobs WHERE lease_id = ? AND status = 'running'", leaseID).
+		Scan(&jobID, &pipelineID, &poolID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLeaseLost
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_jobs SET status = ?, finished_at = ?, lease_expires_at = NULL WHERE id = ?`,
+		status, now, jobID); err != nil {
+		return err
+	}
+	pendingStatus := "skipped"
+	if status == "cancelled" {
+		pendingStatus = "cancelled"
+	}
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_steps SET status = ?
+    WHERE job_id = ? AND status = 'pending'`, pendingStatus, jobID); err != nil {
+		return err
+	}
+	if err := settle(ctx, tx, pipelineID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.finishStepStreams(ctx, jobID)
+	if err := s.Touch(ctx, poolID); err != nil {
+		log.Printf("scheduler: touch pool %s: %v", poolID, err)
+	}
+	return nil
+}
+
+// finishStepStreams ends the live log topics of a job's steps, so viewers of
+// steps that were skipped or cut short are not left waiting.
+func (s *Scheduler) finishStepStreams(ctx context.Context, jobID string) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT id,status FROM pipeline_steps WHERE job_id = ?", jobID)
+	if err != nil {
+		return
+	}
+	type step struct{ id, status string }
+	var steps []step
+	for rows.Next() {
+		var st step
+		if err := rows.Scan(&st.id, &st.status); err == nil {
+			steps = append(steps, st)
+		}
+	}
+	rows.Close()
+	for _, st := range steps {
+		s.Broker.Finish(st.id, st.status)
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.expireLeases(ctx); err != nil {
+				log.Printf("scheduler: expire leases: %v", err)
+			}
+			if err := s.reapRunners(ctx); err != nil {
+				log.Printf("scheduler: reap runners: %v", err)
+			}
+			if err := s.flagUnschedulable(ctx); err != nil {
+				log.Printf("scheduler: flag unschedulable jobs: %v", err)
+			}
+		}
+	}
+}
+
+// expireLeases puts jobs whose runner stopped renewing back in the queue.
+// The old lease ID is dropped so late reports from that runner are refused.
+func (s *Scheduler) expireLeases(ctx context.Context) error {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT id FROM pipeline_jobs
+    WHERE status = 'running' AND lease_expires_at IS NOT NULL AND lease_expires_at < ?`, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	var expired []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		expired = append(expired, id)
+	}
+	rows.Close()
+
+	for _, jobID := range expired {
+		if err := s.requeue(ctx, jobID); err != nil {
+			return err
+		}
+		log.Printf("scheduler: lease expired for job %s, released", jobID)
+	}
+	return nil
+}
+
+func (s *Scheduler) requeue(ctx context.Context, jobID string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var pipelineID string
+	var cancelRequested bool
+	err = tx.QueryRowContext(ctx, `
+    SELECT pipeline_id, cancel_requested_at IS NOT NULL FROM pipeline_jobs
+    WHERE id = ? AND status = 'running'`, jobID).Scan(&pipelineID, &cancelRequested)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if cancelRequested {
+		// The pipeline was cancelled while this job ran, so there is nothing
+		// left to retry.
+		now := time.Now().Unix()
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_jobs SET status = 'cancelled', finished_at = ?, lease_id = '', lease_expires_at = NULL
+      WHERE id = ?`, now, jobID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_steps SET status = 'cancelled', finished_at = COALESCE(finished_at, ?)
+      WHERE job_id = ? AND status IN ('pending','running')`, now, jobID); err != nil {
+			return err
+		}
+		if err := settle(ctx, tx, pipelineID); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_jobs
+      SET status = 'queued', runner_id = '', lease_id = '', lease_expires_at = NULL, started_at = NULL
+      WHERE id = ?`, jobID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_steps SET status = 'pending', started_at = NULL, finished_at = NULL, log_path = NULL, log_lines = 0
+      WHERE job_id = ?`, jobID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.finishStepStreams(ctx, jobID)
+	return nil
+}
+
+func (s *Scheduler) leaseTTL() time.Duration {
+	if s.LeaseTTL <= 0 {
+		return 2 * time.Minute
+	}
+	return s.LeaseTTL
+}
+
+type candidate struct {
+	id        string
+	runsOn    *spec.Labels
+	cpuMillis int
+	memoryMB  int
+	invalid   bool
+}
+
+// readyJobs returns ready jobs ranked by queueScore, oldest pipeline first
+// among equals.
+func readyJobs(ctx context.Context, tx *sql.Tx, now, aging int64, offset int) ([]candidate, error) {
+	rows, err := tx.QueryContext(ctx, queueUsage+`
+    SELECT j.id, COALESCE(j.runs_on, ''), COALESCE(j.cpu_millis, 0), COALESCE(j.memory_mb, 0)
+    FROM pipeline_jobs j
+    JOIN pipelines p ON p.id = j.pipeline_id
+    LEFT JOIN projects pr ON pr.id = p.project_id
+    LEFT JOIN usage u ON u.project_id = p.project_id
+    WHERE j.status = 'queued' AND p.status IN ('queued','running')
+      AND NOT EXISTS (
+        SELECT 1 FROM pipeline_job_needs n
+        JOIN pipeline_jobs d ON d.id = n.needs_job_id
+        WHERE n.job_id = j.id AND d.status != 'success')
+      AND NOT (p.status = 'queued' AND COALESCE(p.concurrency_group,'') != '' AND EXISTS (
+        SELECT 1 FROM pipelines o
+        WHERE o.concurrency_group = p.concurrency_group AND o.status = 'running' AND o.id != p.id))
+    ORDER BY `+queueScore+` DESC, COALESCE(p.created_at, p.started_at), j.position
+    LIMIT ? OFFSET ?`, now-int64(shareWindow/time.Second), now, aging, candidateLimit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var runsOn string
+		if err := rows.Scan(&c.id, &runsOn, &c.cpuMillis, &c.memoryMB); err != nil {
+			return nil, err
+		}
+		if c.runsOn, err = spec.DecodeLabels(runsOn); err != nil {
+			log.Printf("scheduler: job %s has an invalid runs-on expression: %v", c.id, err)
+			c.invalid = true
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+func poolTags(ctx context.Context, tx *sql.Tx, poolID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT tag FROM runner_tags WHERE runner_id = ?", poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func loadJob(ctx context.Context, tx *sql.Tx, jobID string) (*jobspec.Job, error) {
+	job := &jobspec.Job{ID: jobID}
+	var envJSON, inputsJSON string
+	err := tx.QueryRowContext(ctx, `
+    SELECT j.lease_id, j.pipeline_id, p.project_id, j.name, p.commit_hash, p.branch,
+           j.env_json, j.working_directory, j.timeout_seconds, COALESCE(p.inputs,'')
+    FROM pipeline_jobs j
+    JOIN pipelines p ON p.id = j.pipeline_id
+    WHERE j.id = ?`, jobID).
+		Scan(&job.LeaseID, &job.PipelineID, &job.ProjectID, &job.Name, &job.CommitHash, &job.Branch,
+			&envJSON, &job.WorkingDirectory, &job.TimeoutSeconds, &inputsJSON)
+	if err != nil {
+		return nil, err
+	}
+	job.Env = decodeEnv(envJSON)
+	for name, value := range decodeEnv(inputsJSON) {
+		if job.Env == nil {
+			job.Env = make(map[string]string)
+		}
+		job.Env[spec.InputEnv(name)] = value
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+    SELECT id,name,command,env_json,working_directory,timeout_seconds
+    FROM pipeline_steps WHERE job_id = ? ORDER BY position`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var step jobspec.Step
+		var stepEnv string
+		if err := rows.Scan(&step.ID, &step.Name, &step.Run, &stepEnv, &step.WorkingDirectory, &step.TimeoutSeconds); err != nil {
+			return nil, err
+		}
+		step.Env = decodeEnv(stepEnv)
+		job.Steps = append(job.Steps, step)
+	}
+	return job, rows.Err()
+}
+
+// settle skips jobs that can no longer run because a job they need did not
+// succeed, then closes the pipeline once every job is terminal. A pipeline
+// cancelled because a newer run replaced it ends as superseded.
+func settle(ctx context.Context, tx *sql.Tx, pipelineID string) error {
+	now := time.Now().Unix()
+	for {
+		res, err := tx.ExecContext(ctx, `
+      UPDATE pipeline_jobs SET status = 'skipped', finished_at = ?
+      WHERE pipeline_id = ? AND status = 'queued' AND EXISTS (
+        SELECT 1 FROM pipeline_job_needs n
+        JOIN pipeline_jobs d ON d.id = n.needs_job_id
+        WHERE n.job_id = pipeline_jobs.id AND d.status IN ('error','skipped','cancelled'))`,
+			now, pipelineID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			break
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE pipeline_steps SET status = 'skipped'
+    WHERE status = 'pending' AND job_id IN (
+      SELECT id FROM pipeline_jobs WHERE pipeline_id = ? AND status = 'skipped')`, pipelineID); err != nil {
+		return err
+	}
+
+	var open, failed, cancelled int
+	err := tx.QueryRowContext(ctx, `
+    SELECT
+      COALESCE(SUM(CASE WHEN status IN ('queued','running') THEN 1 ELSE 0 END), 0),
+      COALESCE(SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END), 0),
+      COALESCE(SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END), 0)
+    FROM pipeline_jobs WHERE pipeline_id = ?`, pipelineID).Scan(&open, &failed, &cancelled)
+	if err != nil || open > 0 {
+		return err
+	}
+	var superseded bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(superseded_by,'') != '' FROM pipelines WHERE id = ?", pipelineID).Scan(&superseded); err != nil {
+		return err
+	}
+	status := "success"
+	switch {
+	case failed > 0:
+		status = "error"
+	case cancelled > 0 && superseded:
+		status = "superseded"
+	case cancelled > 0:
+		status = "cancelled"
+	}
+	_, err = tx.ExecContext(ctx, `
+    UPDATE pipelines SET status = ?, finished_at = ?
+    WHERE id = ? AND status IN ('queued','running')`, status, now, pipelineID)
+	return err
+}
+
+func decodeEnv(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	var env map[string]string
+	if err := json.Unmarshal([]byte(raw), &env); err != nil || len(env) == 0 {
+		return nil
+	}
+	return env
+}
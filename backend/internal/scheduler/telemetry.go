@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// telemetryHistory is how many samples are kept per runner, an hour at
+	// the default heartbeat interval.
+	telemetryHistory = 240
+	// defaultMinFreeDiskMB applies when MinFreeDiskMB is not set.
+	defaultMinFreeDiskMB = 1024
+)
+
+// Telemetry is one sample of a runner's host, sent with its heartbeat.
+// Sizes are in megabytes; zero means the runner could not tell.
+type Telemetry struct {
+	RecordedAt        int64   `json:"recorded_at"`
+	Load1             float64 `json:"load1"`
+	Load5             float64 `json:"load5"`
+	Load15            float64 `json:"load15"`
+	CPUs              int     `json:"cpus"`
+	MemoryTotalMB     int64   `json:"memory_total_mb"`
+	MemoryAvailableMB int64   `json:"memory_available_mb"`
+	DiskTotalMB       int64   `json:"disk_total_mb"`
+	DiskFreeMB        int64   `json:"disk_free_mb"`
+	RunningJobs       int     `json:"running_jobs"`
+	Version           string  `json:"version"`
+}
+
+// RecordTelemetry stores a sample, keeping the last telemetryHistory per
+// runner, and updates the runner's health. A runner with less than
+// MinFreeDiskMB free in its workspace is unhealthy and leases no jobs until
+// a later sample shows enough room.
+func (s *Scheduler) RecordTelemetry(ctx context.Context, poolID string, t Telemetry) error {
+	if t.RecordedAt == 0 {
+		t.RecordedAt = time.Now().Unix()
+	}
+	if _, err := s.DB.ExecContext(ctx, `
+    INSERT INTO runner_telemetry(id,runner_id,recorded_at,load1,load5,load15,cpus,
+      memory_total_mb,memory_available_mb,disk_total_mb,disk_free_mb,running_jobs,version)
+    VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		uuid.NewString(), poolID, t.RecordedAt, t.Load1, t.Load5, t.Load15, t.CPUs,
+		t.MemoryTotalMB, t.MemoryAvailableMB, t.DiskTotalMB, t.DiskFreeMB, t.RunningJobs, t.Version); err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx, `
+    DELETE FROM runner_telemetry WHERE runner_id = ? AND id NOT IN (
+      SELECT id FROM runner_telemetry WHERE runner_id = ? ORDER BY recorded_at DESC, rowid DESC LIMIT ?)`,
+		poolID, poolID, telemetryHistory); err != nil {
+		return err
+	}
+
+	reason := ""
+	if minFree := s.minFreeDiskMB(); t.DiskTotalMB > 0 && t.DiskFreeMB < minFree {
+		reason = fmt.Sprintf("disk nearly full: %d MB free, %d MB required", t.DiskFreeMB, minFree)
+	}
+	var wasHealthy bool
+	err := s.DB.QueryRowContext(ctx, "SELECT COALESCE(healthy,1) FROM runners WHERE id = ?", poolID).Scan(&wasHealthy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.DB.ExecContext(ctx,
+		"UPDATE runners SET healthy = ?, unhealthy_reason = ? WHERE id = ?", reason == "", reason, poolID); err != nil {
+		return err
+	}
+	if wasHealthy != (reason == "") {
+		if reason != "" {
+			log.Printf("scheduler: pool %s is unhealthy: %s", poolID, reason)
+		} else {
+			log.Printf("scheduler: pool %s is healthy again", poolID)
+		}
+	}
+	return nil
+}
+
+// Telemetry returns the runner's most recent samples, oldest first.
+func (s *Scheduler) Telemetry(ctx context.Context, poolID string, limit int) ([]Telemetry, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+    SELECT recorded_at,load1,load5,load15,cpus,memory_total_mb,memory_available_mb,
+           disk_total_mb,disk_free_mb,running_jobs,version
+    FROM (SELECT *, rowid AS seq FROM runner_telemetry WHERE runner_id = ? ORDER BY recorded_at DESC, seq DESC LIMIT ?)
+    ORDER BY recorded_at, seq`, poolID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	samples := []Telemetry{}
+	for rows.Next() {
+		var t Telemetry
+		if err := rows.Scan(&t.RecordedAt, &t.Load1, &t.Load5, &t.Load15, &t.CPUs, &t.MemoryTotalMB,
+			&t.MemoryAvailableMB, &t.DiskTotalMB, &t.DiskFreeMB, &t.RunningJobs, &t.Version); err != nil {
+			return nil, err
+		}
+		samples = append(samples, t)
+	}
+	return samples, rows.Err()
+}
+
+func (s *Scheduler) minFreeDiskMB() int64 {
+	if s.MinFreeDiskMB <= 0 {
+		return defaultMinFreeDiskMB
+	}
+	return int64(s.MinFreeDiskMB)
+}
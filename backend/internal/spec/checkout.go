@@ -0,0 +1,49 @@
+package spec
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Checkout is how the runner fetches the pipeline's commit into a job's
+// workspace before its steps run. A job without one gets DefaultCheckout.
+type Checkout struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// Depth is how many commits of history to fetch; zero fetches all.
+	Depth int `json:"depth"`
+	// Filter is a partial clone filter such as "blob:none".
+	Filter string `json:"filter,omitempty"`
+	// Submodules is "", "true" or "recursive".
+	Submodules string `json:"submodules,omitempty"`
+	// Credentials names the secret holding the token, or "user:token", used
+	// for the repository. It defaults to GIT_TOKEN.
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// DefaultCheckout is a shallow clone of the commit without submodules.
+var DefaultCheckout = Checkout{Depth: 1}
+
+var filterPattern = regexp.MustCompile(`^(blob:none|blob:limit=[0-9]+[kmg]?|tree:[0-9]+)$`)
+
+// Encode returns the checkout as stored on a job, or "" for the default.
+func (c *Checkout) Encode() string {
+	if c == nil {
+		return ""
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+func DecodeCheckout(raw string) (*Checkout, error) {
+	checkout := DefaultCheckout
+	if raw == "" {
+		return &checkout, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &checkout); err != nil {
+		return nil, err
+	}
+	return &checkout, nil
+}
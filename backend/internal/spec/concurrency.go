@@ -0,0 +1,50 @@
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Concurrency puts the pipeline in a group. A new run in a group supersedes
+// the group's queued runs and, with CancelInProgress, its running ones too.
+type Concurrency struct {
+	Group            string
+	CancelInProgress bool
+}
+
+// ConcurrencyVars are the values a group expression can refer to, as in
+// "${{ project }}/${{ branch }}", besides the inputs as ${{ inputs.<name> }}.
+var ConcurrencyVars = []string{"project", "project_id", "branch", "commit", "triggered_by"}
+
+var expressionPattern = regexp.MustCompile(`\$\{\{\s*([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_-]+)?)\s*\}\}`)
+
+// Key evaluates the group expression. Groups are shared by every project,
+// so a group meant for one project should include ${{ project }}.
+func (c *Concurrency) Key(vars map[string]string) string {
+	if c == nil {
+		return ""
+	}
+	return expressionPattern.ReplaceAllStringFunc(c.Group, func(match string) string {
+		return vars[expressionPattern.FindStringSubmatch(match)[1]]
+	})
+}
+
+func validateExpression(expr string, inputs []*Input) error {
+	for _, match := range expressionPattern.FindAllStringSubmatch(expr, -1) {
+		if name, ok := strings.CutPrefix(match[1], "inputs."); ok {
+			if !slices.ContainsFunc(inputs, func(input *Input) bool { return input.Name == name }) {
+				return fmt.Errorf("unknown input %q in %q", name, expr)
+			}
+			continue
+		}
+		if !slices.Contains(ConcurrencyVars, match[1]) {
+			return fmt.Errorf("unknown variable %q in %q (expected %s)", match[1], expr, strings.Join(ConcurrencyVars, ", "))
+		}
+	}
+	if strings.Contains(expressionPattern.ReplaceAllString(expr, ""), "${{") {
+		return fmt.Errorf("malformed expression in %q", expr)
+	}
+	return nil
+}
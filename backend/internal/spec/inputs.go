@@ -0,0 +1,201 @@
+package spec
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Input types.
+const (
+	InputString  = "string"
+	InputBoolean = "boolean"
+	InputNumber  = "number"
+	InputChoice  = "choice"
+)
+
+// Input is a value a run is started with, declared under inputs. Steps see
+// it as OA_INPUT_<NAME>, and ${{ inputs.<name> }} can be used in env values
+// and working directories. Commands get no expressions, since pasting a
+// value into a shell command would let it run as code.
+type Input struct {
+	Name        string
+	Type        string
+	Description string
+	// Required inputs without a default must be given when a run is started
+	// by hand.
+	Required bool
+	// Default is used when the input is not given. Without a declared one it
+	// is the type's zero value: "", false, 0 or the first option.
+	Default string
+	Options []string
+
+	hasDefault bool
+}
+
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// inputPattern matches the input references in env values and working
+// directories.
+var inputPattern = regexp.MustCompile(`\$\{\{\s*inputs\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// InputError is a problem with one of the values a run was started with.
+type InputError struct {
+	Input   string `json:"input"`
+	Message string `json:"message"`
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("input %q: %s", e.Input, e.Message)
+}
+
+type InputErrors []*InputError
+
+func (l InputErrors) Error() string {
+	parts := make([]string, 0, len(l))
+	for _, err := range l {
+		parts = append(parts, err.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// InputEnv is the variable an input is passed to the steps in, OA_INPUT_ and
+// the name upper-cased with anything but letters and digits replaced by
+// underscores.
+func InputEnv(name string) string {
+	return "OA_INPUT_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (p *Pipeline) Input(name string) *Input {
+	for _, input := range p.Inputs {
+		if input.Name == name {
+			return input
+		}
+	}
+	return nil
+}
+
+// ResolveInputs checks the given values against the declared inputs and
+// returns every input's value, with defaults filled in and booleans and
+// numbers in canonical form. Without enforceRequired, required inputs that
+// were not given get their default too. Problems are returned as
+// InputErrors.
+func (p *Pipeline) ResolveInputs(given map[string]string, enforceRequired bool) (map[string]string, error) {
+	var errs InputErrors
+	names := make([]string, 0, len(given))
+	for name := range given {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if p.Input(name) == nil {
+			errs = append(errs, &InputError{Input: name, Message: "is not declared by the pipeline spec"})
+		}
+	}
+	values := make(map[string]string, len(p.Inputs))
+	for _, input := range p.Inputs {
+		value, ok := given[input.Name]
+		if !ok {
+			if enforceRequired && input.MustBeGiven() {
+				errs = append(errs, &InputError{Input: input.Name, Message: "is required"})
+			}
+			values[input.Name] = input.Default
+			continue
+		}
+		normalized, err := input.normalize(value)
+		if err != nil {
+			errs = append(errs, &InputError{Input: input.Name, Message: err.Error()})
+			continue
+		}
+		values[input.Name] = normalized
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return values, nil
+}
+
+// MustBeGiven reports whether a run started by hand has to give the input:
+// it is required and declares no default.
+func (in *Input) MustBeGiven() bool {
+	return in.Required && !in.hasDefault
+}
+
+func (in *Input) normalize(value string) (string, error) {
+	switch in.Type {
+	case InputBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("expected true or false, got %q", value)
+		}
+		return strconv.FormatBool(b), nil
+	case InputNumber:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return "", fmt.Errorf("expected a number, got %q", value)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case InputChoice:
+		if !slices.Contains(in.Options, value) {
+			return "", fmt.Errorf("expected one of %s, got %q", strings.Join(in.Options, ", "), value)
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// Expand replaces the ${{ inputs.<name> }} references in the env and
+// working directories of the jobs and steps with the run's input values.
+// Inputs that would take a working directory out of the workspace are
+// returned as InputErrors.
+func (p *Pipeline) Expand(inputs map[string]string) error {
+	var errs InputErrors
+	expand := func(s string) string {
+		return inputPattern.ReplaceAllStringFunc(s, func(match string) string {
+			return inputs[inputPattern.FindStringSubmatch(match)[1]]
+		})
+	}
+	expandEnv := func(env map[string]string) map[string]string {
+		if len(env) == 0 {
+			return env
+		}
+		expanded := make(map[string]string, len(env))
+		for key, value := range env {
+			expanded[key] = expand(value)
+		}
+		return expanded
+	}
+	expandDir := func(dir string) string {
+		expanded := expand(dir)
+		if !LocalDir(expanded) {
+			for _, match := range inputPattern.FindAllStringSubmatch(dir, -1) {
+				errs = append(errs, &InputError{Input: match[1], Message: "takes working-directory out of the workspace"})
+			}
+		}
+		return expanded
+	}
+	for _, job := range p.Jobs {
+		job.Env = expandEnv(job.Env)
+		job.WorkingDirectory = expandDir(job.WorkingDirectory)
+		for _, step := range job.Steps {
+			step.Env = expandEnv(step.Env)
+			step.WorkingDirectory = expandDir(step.WorkingDirectory)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
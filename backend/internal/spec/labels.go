@@ -0,0 +1,58 @@
+package spec
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Labels is a runs-on expression over runner tags: every tag in AllOf, at
+// least one of AnyOf when it is set, and none of Not.
+type Labels struct {
+	AllOf []string `json:"all_of,omitempty"`
+	AnyOf []string `json:"any_of,omitempty"`
+	Not   []string `json:"not,omitempty"`
+}
+
+func (l *Labels) Match(tags []string) bool {
+	if l == nil {
+		return true
+	}
+	for _, tag := range l.AllOf {
+		if !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	if len(l.AnyOf) > 0 && !slices.ContainsFunc(l.AnyOf, func(tag string) bool { return slices.Contains(tags, tag) }) {
+		return false
+	}
+	for _, tag := range l.Not {
+		if slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode returns the expression as stored on a job, or "" when the job can
+// run anywhere.
+func (l *Labels) Encode() string {
+	if l == nil {
+		return ""
+	}
+	raw, err := json.Marshal(l)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+func DecodeLabels(raw string) (*Labels, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var labels Labels
+	if err := json.Unmarshal([]byte(raw), &labels); err != nil {
+		return nil, err
+	}
+	return &labels, nil
+}
@@ -0,0 +1,671 @@
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"openaction/pkg/quantity"
+)
+
+var (
+	namePattern      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	yamlErrorPattern = regexp.MustCompile(`line (\d+): `)
+)
+
+type parser struct {
+	errs ErrorList
+	// inputs are the pipeline's declared inputs, parsed first so that
+	// references to them can be checked anywhere.
+	inputs []*Input
+}
+
+func Parse(data []byte) (*Pipeline, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, ErrorList{syntaxError(err)}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, ErrorList{{Line: 1, Column: 1, Message: "pipeline spec is empty"}}
+	}
+
+	p := &parser{}
+	pipeline := p.pipeline(doc.Content[0])
+	if len(p.errs) == 0 {
+		p.validateGraph(pipeline)
+	}
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+	return pipeline, nil
+}
+
+func syntaxError(err error) *Error {
+	line := 1
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+	if match := yamlErrorPattern.FindStringSubmatch(message); match != nil {
+		if value, convErr := strconv.Atoi(match[1]); convErr == nil {
+			line = value
+		}
+		message = strings.Replace(message, match[0], "", 1)
+	}
+	return &Error{Line: line, Column: 1, Message: message}
+}
+
+func (p *parser) errorf(node *yaml.Node, format string, args ...any) {
+	p.errs = append(p.errs, &Error{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) pipeline(node *yaml.Node) *Pipeline {
+	pipeline := &Pipeline{}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "pipeline spec must be a mapping")
+		return pipeline
+	}
+
+	p.fields(node, func(key string, keyNode, value *yaml.Node) {
+		if key == "inputs" {
+			p.inputs = p.inputList(value)
+		}
+	})
+	pipeline.Inputs = p.inputs
+
+	var versionNode, jobsNode *yaml.Node
+	p.fields(node, func(key string, keyNode, value *yaml.Node) {
+		switch key {
+		case "version":
+			versionNode = value
+			pipeline.Version = p.integer(value)
+		case "name":
+			pipeline.Name = p.str(value)
+		case "env":
+			pipeline.Env = p.env(value)
+		case "concurrency":
+			pipeline.Concurrency = p.concurrency(value)
+		case "checkout":
+			pipeline.Checkout = p.checkout(value)
+		case "inputs":
+		case "jobs":
+			jobsNode = value
+		default:
+			p.errorf(keyNode, "unknown field %q", key)
+		}
+	})
+
+	if versionNode == nil {
+		p.errorf(node, "missing required field \"version\"")
+	} else if pipeline.Version != CurrentVersion {
+		p.errorf(versionNode, "unsupported spec version %d (expected %d)", pipeline.Version, CurrentVersion)
+	}
+	if jobsNode == nil {
+		p.errorf(node, "missing required field \"jobs\"")
+		return pipeline
+	}
+	pipeline.Jobs = p.jobs(jobsNode)
+	for _, job := range pipeline.Jobs {
+		job.Env = mergeEnv(pipeline.Env, job.Env)
+		if job.Checkout == nil {
+			job.Checkout = pipeline.Checkout
+		}
+	}
+	return pipeline
+}
+
+func (p *parser) jobs(node *yaml.Node) []*Job {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "jobs must be a mapping of job name to job")
+		return nil
+	}
+	if len(node.Content) == 0 {
+		p.errorf(node, "jobs must define at least one job")
+		return nil
+	}
+	var jobs []*Job
+	seen := make(map[string]bool)
+	p.fields(node, func(name string, keyNode, value *yaml.Node) {
+		if !namePattern.MatchString(name) {
+			p.errorf(keyNode, "invalid job name %q", name)
+		}
+		if seen[name] {
+			p.errorf(keyNode, "duplicate job %q", name)
+		}
+		seen[name] = true
+		job := p.job(value)
+		job.Name = name
+		job.line, job.column = keyNode.Line, keyNode.Column
+		jobs = append(jobs, job)
+	})
+	return jobs
+}
+
+func (p *parser) job(node *yaml.Node) *Job {
+	job := &Job{}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "job must be a mapping")
+		return job
+	}
+	var stepsNode *yaml.Node
+	p.fields(node, func(key string, keyNode, value *yaml.Node) {
+		switch key {
+		case "needs":
+			job.Needs = p.stringList(value)
+		case "env":
+			job.Env = p.env(value)
+		case "working-directory":
+			job.WorkingDirectory = p.workingDirectory(value)
+		case "timeout":
+			job.Timeout = p.duration(value)
+		case "runs-on":
+			job.RunsOn = p.labels(value)
+		case "resources":
+			job.Resources = p.resources(value)
+		case "checkout":
+			job.Checkout = p.checkout(value)
+		case "steps":
+			stepsNode = value
+		default:
+			p.errorf(keyNode, "unknown job field %q", key)
+		}
+	})
+	if stepsNode == nil {
+		p.errorf(node, "job must define \"steps\"")
+		return job
+	}
+	job.Steps = p.steps(stepsNode)
+	return job
+}
+
+func (p *parser) steps(node *yaml.Node) []*Step {
+	if node.Kind != yaml.SequenceNode {
+		p.errorf(node, "steps must be a list")
+		return nil
+	}
+	if len(node.Content) == 0 {
+		p.errorf(node, "steps must contain at least one step")
+		return nil
+	}
+	var steps []*Step
+	seen := make(map[string]bool)
+	for index, item := range node.Content {
+		step := p.step(item)
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", index+1)
+		}
+		if seen[step.Name] {
+			p.errorf(item, "duplicate step name %q", step.Name)
+		}
+		seen[step.Name] = true
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func (p *parser) step(node *yaml.Node) *Step {
+	step := &Step{}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "step must be a mapping")
+		return step
+	}
+	p.fields(node, func(key string, keyNode, value *yaml.Node) {
+		switch key {
+		case "name":
+			step.Name = p.str(value)
+		case "run":
+			if step.Run = p.str(value); inputPattern.MatchString(step.Run) {
+				p.errorf(value, "inputs cannot be used in run, which is a shell command; use $OA_INPUT_<NAME> or an env entry instead")
+			}
+		case "env":
+			step.Env = p.env(value)
+		case "working-directory":
+			step.WorkingDirectory = p.workingDirectory(value)
+		case "timeout":
+			step.Timeout = p.duration(value)
+		default:
+			p.errorf(keyNode, "unknown step field %q", key)
+		}
+	})
+	if strings.TrimSpace(step.Run) == "" {
+		p.errorf(node, "step must define \"run\"")
+	}
+	return step
+}
+
+func (p *parser) validateGraph(pipeline *Pipeline) {
+	for _, job := range pipeline.Jobs {
+		for _, need := range job.Needs {
+			if need == job.Name {
+				p.errs = append(p.errs, &Error{Line: job.line, Column: job.column, Message: fmt.Sprintf("job %q cannot need itself", job.Name)})
+			} else if pipeline.Job(need) == nil {
+				p.errs = append(p.errs, &Error{Line: job.line, Column: job.column, Message: fmt.Sprintf("job %q needs unknown job %q", job.Name, need)})
+			}
+		}
+	}
+	if len(p.errs) > 0 {
+		return
+	}
+	if ordered := pipeline.Order(); len(ordered) < len(pipeline.Jobs) {
+		placed := make(map[string]bool, len(ordered))
+		for _, job := range ordered {
+			placed[job.Name] = true
+		}
+		for _, job := range pipeline.Jobs {
+			if !placed[job.Name] {
+				p.errs = append(p.errs, &Error{Line: job.line, Column: job.column, Message: fmt.Sprintf("job %q is part of a dependency cycle", job.Name)})
+			}
+		}
+	}
+}
+
+func (p *parser) fields(node *yaml.Node, fn func(key string, keyNode, value *yaml.Node)) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, value := node.Content[i], node.Content[i+1]
+		if keyNode.Kind != yaml.ScalarNode {
+			p.errorf(keyNode, "mapping keys must be strings")
+			continue
+		}
+		fn(keyNode.Value, keyNode, value)
+	}
+}
+
+func (p *parser) str(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected a string")
+		return ""
+	}
+	return node.Value
+}
+
+func (p *parser) integer(node *yaml.Node) int {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected an integer")
+		return 0
+	}
+	value, err := strconv.Atoi(node.Value)
+	if err != nil {
+		p.errorf(node, "expected an integer, got %q", node.Value)
+		return 0
+	}
+	return value
+}
+
+func (p *parser) boolean(node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected true or false")
+		return false
+	}
+	value, err := strconv.ParseBool(node.Value)
+	if err != nil {
+		p.errorf(node, "expected true or false, got %q", node.Value)
+		return false
+	}
+	return value
+}
+
+func (p *parser) duration(node *yaml.Node) time.Duration {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected a duration such as \"10m\"")
+		return 0
+	}
+	value, err := time.ParseDuration(node.Value)
+	if err != nil || value <= 0 {
+		p.errorf(node, "invalid duration %q", node.Value)
+		return 0
+	}
+	return value
+}
+
+func (p *parser) stringList(node *yaml.Node) []string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value == "" {
+			return nil
+		}
+		return []string{node.Value}
+	case yaml.SequenceNode:
+		var items []string
+		for _, item := range node.Content {
+			if value := p.str(item); value != "" {
+				items = append(items, value)
+			}
+		}
+		return items
+	default:
+		p.errorf(node, "expected a string or a list of strings")
+		return nil
+	}
+}
+
+// labels accepts a single tag, a list of tags that must all be present, or a
+// mapping with all-of, any-of and not.
+func (p *parser) labels(node *yaml.Node) *Labels {
+	labels := &Labels{}
+	switch node.Kind {
+	case yaml.ScalarNode, yaml.SequenceNode:
+		labels.AllOf = p.stringList(node)
+	case yaml.MappingNode:
+		p.fields(node, func(key string, keyNode, value *yaml.Node) {
+			switch key {
+			case "all-of":
+				labels.AllOf = p.stringList(value)
+			case "any-of":
+				labels.AnyOf = p.stringList(value)
+			case "not":
+				labels.Not = p.stringList(value)
+			default:
+				p.errorf(keyNode, "unknown runs-on field %q (expected all-of, any-of or not)", key)
+			}
+		})
+	default:
+		p.errorf(node, "runs-on must be a tag, a list of tags or a mapping")
+		return nil
+	}
+	if len(labels.AllOf) == 0 && len(labels.AnyOf) == 0 && len(labels.Not) == 0 {
+		p.errorf(node, "runs-on must name at least one tag")
+		return nil
+	}
+	return labels
+}
+
+// resources accepts a class name, or a mapping with an optional class whose
+// cpu and memory can be overridden.
+func (p *parser) resources(node *yaml.Node) *Resources {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		class, ok := ResourceClasses[node.Value]
+		if !ok {
+			p.errorf(node, "unknown resource class %q (expected %s)", node.Value, strings.Join(resourceClassNames(), ", "))
+			return nil
+		}
+		return &class
+	case yaml.MappingNode:
+		resources := &Resources{}
+		p.fields(node, func(key string, keyNode, value *yaml.Node) {
+			switch key {
+			case "class":
+				class, ok := ResourceClasses[p.str(value)]
+				if !ok {
+					p.errorf(value, "unknown resource class %q (expected %s)", value.Value, strings.Join(resourceClassNames(), ", "))
+					return
+				}
+				resources.Class = class.Class
+				if resources.CPUMillis == 0 {
+					resources.CPUMillis = class.CPUMillis
+				}
+				if resources.MemoryMB == 0 {
+					resources.MemoryMB = class.MemoryMB
+				}
+			case "cpu":
+				cpu, err := quantity.ParseCPU(p.str(value))
+				if err != nil {
+					p.errorf(value, "%v (expected cores such as 2 or 500m)", err)
+					return
+				}
+				resources.CPUMillis = cpu
+			case "memory":
+				memory, err := quantity.ParseMemory(p.str(value))
+				if err != nil {
+					p.errorf(value, "%v (expected a size such as 4Gi or 512Mi)", err)
+					return
+				}
+				resources.MemoryMB = memory
+			default:
+				p.errorf(keyNode, "unknown resources field %q (expected class, cpu or memory)", key)
+			}
+		})
+		return resources
+	default:
+		p.errorf(node, "resources must be a class name or a mapping")
+		return nil
+	}
+}
+
+// concurrency accepts a group expression, or a mapping with group and
+// cancel-in-progress.
+func (p *parser) concurrency(node *yaml.Node) *Concurrency {
+	concurrency := &Concurrency{}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		concurrency.Group = p.group(node)
+	case yaml.MappingNode:
+		var groupNode *yaml.Node
+		p.fields(node, func(key string, keyNode, value *yaml.Node) {
+			switch key {
+			case "group":
+				groupNode = value
+				concurrency.Group = p.group(value)
+			case "cancel-in-progress":
+				concurrency.CancelInProgress = p.boolean(value)
+			default:
+				p.errorf(keyNode, "unknown concurrency field %q (expected group or cancel-in-progress)", key)
+			}
+		})
+		if groupNode == nil {
+			p.errorf(node, "concurrency must define \"group\"")
+		}
+	default:
+		p.errorf(node, "concurrency must be a group or a mapping")
+	}
+	if concurrency.Group == "" {
+		return nil
+	}
+	return concurrency
+}
+
+// checkout accepts false to skip the checkout, or a mapping with depth,
+// filter, submodules and credentials. Fields left out keep their defaults.
+func (p *parser) checkout(node *yaml.Node) *Checkout {
+	checkout := DefaultCheckout
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if p.boolean(node) {
+			return &checkout
+		}
+		return &Checkout{Disabled: true}
+	case yaml.MappingNode:
+		p.fields(node, func(key string, keyNode, value *yaml.Node) {
+			switch key {
+			case "depth":
+				if checkout.Depth = p.integer(value); checkout.Depth < 0 {
+					p.errorf(value, "depth must not be negative (0 fetches the full history)")
+				}
+			case "filter":
+				if checkout.Filter = p.str(value); !filterPattern.MatchString(checkout.Filter) {
+					p.errorf(value, "invalid filter %q (expected blob:none, blob:limit=<size> or tree:<depth>)", checkout.Filter)
+				}
+			case "submodules":
+				switch submodules := p.str(value); submodules {
+				case "true", "recursive":
+					checkout.Submodules = submodules
+				case "false":
+				default:
+					p.errorf(value, "submodules must be true, false or recursive")
+				}
+			case "credentials":
+				if checkout.Credentials = p.str(value); checkout.Credentials == "" {
+					p.errorf(value, "credentials must name a secret")
+				}
+			default:
+				p.errorf(keyNode, "unknown checkout field %q (expected depth, filter, submodules or credentials)", key)
+			}
+		})
+	default:
+		p.errorf(node, "checkout must be false or a mapping")
+	}
+	return &checkout
+}
+
+func (p *parser) group(node *yaml.Node) string {
+	group := strings.TrimSpace(p.str(node))
+	if group == "" {
+		p.errorf(node, "concurrency group must not be empty")
+		return ""
+	}
+	if err := validateExpression(group, p.inputs); err != nil {
+		p.errorf(node, "%v", err)
+		return ""
+	}
+	return group
+}
+
+// inputList accepts a mapping of input name to a mapping with type,
+// description, required, default and, for choices, options.
+func (p *parser) inputList(node *yaml.Node) []*Input {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "inputs must be a mapping of input name to input")
+		return nil
+	}
+	var inputs []*Input
+	seen := make(map[string]bool)
+	envNames := make(map[string]string)
+	p.fields(node, func(name string, keyNode, value *yaml.Node) {
+		if !inputNamePattern.MatchString(name) {
+			p.errorf(keyNode, "invalid input name %q", name)
+		}
+		if seen[name] {
+			p.errorf(keyNode, "duplicate input %q", name)
+		} else if other, ok := envNames[InputEnv(name)]; ok {
+			p.errorf(keyNode, "input %q and input %q would both be passed to steps as %s", other, name, InputEnv(name))
+		} else {
+			envNames[InputEnv(name)] = name
+		}
+		seen[name] = true
+		input := p.input(value)
+		input.Name = name
+		inputs = append(inputs, input)
+	})
+	return inputs
+}
+
+func (p *parser) input(node *yaml.Node) *Input {
+	input := &Input{Type: InputString}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "input must be a mapping")
+		return input
+	}
+	var defaultNode, optionsNode *yaml.Node
+	p.fields(node, func(key string, keyNode, value *yaml.Node) {
+		switch key {
+		case "type":
+			switch kind := p.str(value); kind {
+			case InputString, InputNumber, InputChoice:
+				input.Type = kind
+			case InputBoolean, "bool":
+				input.Type = InputBoolean
+			default:
+				p.errorf(value, "unknown input type %q (expected string, boolean, number or choice)", kind)
+			}
+		case "description":
+			input.Description = p.str(value)
+		case "required":
+			input.Required = p.boolean(value)
+		case "default":
+			defaultNode = value
+		case "options":
+			optionsNode = value
+			input.Options = p.stringList(value)
+		default:
+			p.errorf(keyNode, "unknown input field %q (expected type, description, required, default or options)", key)
+		}
+	})
+	switch {
+	case input.Type == InputChoice && len(input.Options) == 0:
+		p.errorf(node, "choice input must list its options")
+		return input
+	case input.Type != InputChoice && optionsNode != nil:
+		p.errorf(optionsNode, "options are only allowed for choice inputs")
+	}
+	if defaultNode == nil {
+		switch input.Type {
+		case InputBoolean:
+			input.Default = "false"
+		case InputNumber:
+			input.Default = "0"
+		case InputChoice:
+			input.Default = input.Options[0]
+		}
+		return input
+	}
+	value, err := input.normalize(p.str(defaultNode))
+	if err != nil {
+		p.errorf(defaultNode, "invalid default: %v", err)
+	}
+	input.Default, input.hasDefault = value, true
+	return input
+}
+
+// expression reads a string that may refer to inputs as ${{ inputs.<name> }}.
+func (p *parser) expression(node *yaml.Node) string {
+	value := p.str(node)
+	for _, match := range inputPattern.FindAllStringSubmatch(value, -1) {
+		if !slices.ContainsFunc(p.inputs, func(input *Input) bool { return input.Name == match[1] }) {
+			p.errorf(node, "unknown input %q", match[1])
+		}
+	}
+	return value
+}
+
+func (p *parser) workingDirectory(node *yaml.Node) string {
+	dir := p.expression(node)
+	if !LocalDir(dir) {
+		p.errorf(node, "working-directory must be a relative path inside the workspace")
+	}
+	return dir
+}
+
+// LocalDir reports whether dir, joined to the workspace, stays inside it:
+// it is not absolute and has no .. element.
+func LocalDir(dir string) bool {
+	if strings.HasPrefix(dir, "/") || strings.HasPrefix(dir, `\`) || (len(dir) >= 2 && dir[1] == ':') {
+		return false
+	}
+	for _, part := range strings.FieldsFunc(dir, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) env(node *yaml.Node) map[string]string {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "env must be a mapping")
+		return nil
+	}
+	env := make(map[string]string)
+	p.fields(node, func(key string, keyNode, value *yaml.Node) {
+		if !envNamePattern.MatchString(key) {
+			p.errorf(keyNode, "invalid environment variable name %q", key)
+			return
+		}
+		if value.Kind != yaml.ScalarNode {
+			p.errorf(value, "environment variable %q must be a scalar", key)
+			return
+		}
+		if i := slices.IndexFunc(p.inputs, func(input *Input) bool { return InputEnv(input.Name) == key }); i >= 0 {
+			p.errorf(keyNode, "environment variable %q is set from input %q", key, p.inputs[i].Name)
+			return
+		}
+		env[key] = p.expression(value)
+	})
+	return env
+}
+
+func mergeEnv(base, override map[string]string) map[string]string {
+	if len(base) == 0 {
+		return override
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
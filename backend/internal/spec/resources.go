@@ -0,0 +1,27 @@
+package spec
+
+import "slices"
+
+// Resources is what a job needs from the runner it is leased to. A zero
+// amount asks for nothing beyond a job slot.
+type Resources struct {
+	Class     string `json:"class,omitempty"`
+	CPUMillis int    `json:"cpu_millis"`
+	MemoryMB  int    `json:"memory_mb"`
+}
+
+// ResourceClasses are the named sizes a job can ask for.
+var ResourceClasses = map[string]Resources{
+	"small":  {Class: "small", CPUMillis: 1000, MemoryMB: 2048},
+	"medium": {Class: "medium", CPUMillis: 2000, MemoryMB: 4096},
+	"large":  {Class: "large", CPUMillis: 4000, MemoryMB: 8192},
+}
+
+func resourceClassNames() []string {
+	names := make([]string, 0, len(ResourceClasses))
+	for name := range ResourceClasses {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,107 @@
+package spec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	FileName       = ".openaction.yml"
+	CurrentVersion = 1
+)
+
+type Pipeline struct {
+	Version     int
+	Name        string
+	Env         map[string]string
+	Concurrency *Concurrency
+	Checkout    *Checkout
+	Inputs      []*Input
+	Jobs        []*Job
+}
+
+type Job struct {
+	Name             string
+	Needs            []string
+	Env              map[string]string
+	WorkingDirectory string
+	Timeout          time.Duration
+	RunsOn           *Labels
+	Resources        *Resources
+	Checkout         *Checkout
+	Steps            []*Step
+
+	line   int
+	column int
+}
+
+type Step struct {
+	Name             string
+	Run              string
+	Env              map[string]string
+	WorkingDirectory string
+	Timeout          time.Duration
+}
+
+type Error struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	parts := make([]string, 0, len(l))
+	for _, err := range l {
+		parts = append(parts, err.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (p *Pipeline) Job(name string) *Job {
+	for _, job := range p.Jobs {
+		if job.Name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Order returns the jobs sorted so that every job comes after the jobs it
+// needs. Jobs without a dependency between them keep their file order.
+func (p *Pipeline) Order() []*Job {
+	remaining := make(map[string]int, len(p.Jobs))
+	for _, job := range p.Jobs {
+		remaining[job.Name] = len(job.Needs)
+	}
+	done := make(map[string]bool, len(p.Jobs))
+	ordered := make([]*Job, 0, len(p.Jobs))
+	for len(ordered) < len(p.Jobs) {
+		progressed := false
+		for _, job := range p.Jobs {
+			if done[job.Name] || remaining[job.Name] > 0 {
+				continue
+			}
+			done[job.Name] = true
+			ordered = append(ordered, job)
+			progressed = true
+			for _, other := range p.Jobs {
+				for _, need := range other.Needs {
+					if need == job.Name {
+						remaining[other.Name]--
+					}
+				}
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// github signs the body with X-Hub-Signature-256.
+type github struct{}
+
+func (github) Verify(r *http.Request, body []byte, secret string) error {
+	return verifyHMAC(strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="), body, secret)
+}
+
+func (github) DeliveryID(r *http.Request) string {
+	return r.Header.Get("X-GitHub-Delivery")
+}
+
+func (github) Parse(r *http.Request, body []byte) (*Event, error) {
+	return parseHubEvent(r.Header.Get("X-GitHub-Event"), body, []string{"opened", "reopened", "synchronize"})
+}
+
+// gitea signs the body with X-Gitea-Signature and otherwise sends payloads
+// shaped like GitHub's.
+type gitea struct{}
+
+func (gitea) Verify(r *http.Request, body []byte, secret string) error {
+	return verifyHMAC(r.Header.Get("X-Gitea-Signature"), body, secret)
+}
+
+func (gitea) DeliveryID(r *http.Request) string {
+	return r.Header.Get("X-Gitea-Delivery")
+}
+
+func (gitea) Parse(r *http.Request, body []byte) (*Event, error) {
+	return parseHubEvent(r.Header.Get("X-Gitea-Event"), body, []string{"opened", "reopened", "synchronized"})
+}
+
+func parseHubEvent(kind string, body []byte, prActions []string) (*Event, error) {
+	switch kind {
+	case "push":
+		var payload struct {
+			Ref        string   `json:"ref"`
+			After      string   `json:"after"`
+			HeadCommit *commit  `json:"head_commit"`
+			Commits    []commit `json:"commits"`
+			Sender     struct {
+				Login string `json:"login"`
+			} `json:"sender"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		return refEvent(payload.Ref, payload.After, headMessage(payload.HeadCommit, payload.Commits, payload.After),
+			payload.Sender.Login), nil
+	case "pull_request":
+		var payload struct {
+			Action      string `json:"action"`
+			Number      int    `json:"number"`
+			PullRequest struct {
+				Title string `json:"title"`
+				Head  struct {
+					Ref string `json:"ref"`
+					SHA string `json:"sha"`
+				} `json:"head"`
+			} `json:"pull_request"`
+			Sender struct {
+				Login string `json:"login"`
+			} `json:"sender"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		event := &Event{
+			Kind:    PullRequest,
+			Ref:     payload.PullRequest.Head.Ref,
+			Commit:  payload.PullRequest.Head.SHA,
+			Message: payload.PullRequest.Title,
+			Sender:  payload.Sender.Login,
+			Number:  payload.Number,
+		}
+		if !slices.Contains(prActions, payload.Action) {
+			event.Ignore = "pull request " + payload.Action
+		}
+		return event, nil
+	default:
+		return &Event{Ignore: "unsupported event " + kind}, nil
+	}
+}
+
+// gitlab sends the secret itself in X-Gitlab-Token.
+type gitlab struct{}
+
+func (gitlab) Verify(r *http.Request, body []byte, secret string) error {
+	return verifyToken(r.Header.Get("X-Gitlab-Token"), secret)
+}
+
+func (gitlab) DeliveryID(r *http.Request) string {
+	if id := r.Header.Get("Idempotency-Key"); id != "" {
+		return id
+	}
+	return r.Header.Get("X-Gitlab-Event-UUID")
+}
+
+func (gitlab) Parse(r *http.Request, body []byte) (*Event, error) {
+	switch kind := r.Header.Get("X-Gitlab-Event"); kind {
+	case "Push Hook", "Tag Push Hook":
+		var payload struct {
+			Ref         string   `json:"ref"`
+			After       string   `json:"after"`
+			CheckoutSHA string   `json:"checkout_sha"`
+			Commits     []commit `json:"commits"`
+			UserName    string   `json:"user_username"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		// A tag push's after is the tag object; checkout_sha is the commit.
+		sha := payload.After
+		if payload.CheckoutSHA != "" {
+			sha = payload.CheckoutSHA
+		}
+		return refEvent(payload.Ref, sha, headMessage(nil, payload.Commits, sha), payload.UserName), nil
+	case "Merge Request Hook":
+		var payload struct {
+			User struct {
+				Username string `json:"username"`
+			} `json:"user"`
+			Attributes struct {
+				Action       string `json:"action"`
+				IID          int    `json:"iid"`
+				Title        string `json:"title"`
+				SourceBranch string `json:"source_branch"`
+				OldRev       string `json:"oldrev"`
+				LastCommit   commit `json:"last_commit"`
+			} `json:"object_attributes"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		attrs := payload.Attributes
+		event := &Event{
+			Kind:    PullRequest,
+			Ref:     attrs.SourceBranch,
+			Commit:  attrs.LastCommit.ID,
+			Message: attrs.Title,
+			Sender:  payload.User.Username,
+			Number:  attrs.IID,
+		}
+		// An update without oldrev changed the title, labels and the
+		// like, not the commits.
+		switch {
+		case attrs.Action == "update" && attrs.OldRev == "":
+			event.Ignore = "merge request updated without new commits"
+		case attrs.Action != "open" && attrs.Action != "reopen" && attrs.Action != "update":
+			event.Ignore = "merge request " + attrs.Action
+		}
+		return event, nil
+	default:
+		return &Event{Ignore: "unsupported event " + kind}, nil
+	}
+}
@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown webhook provider")
+	ErrBadSignature    = errors.New("invalid webhook signature")
+)
+
+// Event kinds, also used as the triggered_by of the pipelines they start.
+const (
+	Push        = "push"
+	Tag         = "tag"
+	PullRequest = "pull_request"
+)
+
+// Event is a push, tag or pull request delivery normalized across
+// providers.
+type Event struct {
+	Kind string
+	// Ref is the branch pushed to, the tag, or a pull request's source
+	// branch.
+	Ref    string
+	Commit string
+	// Message is the head commit's message, or a pull request's title.
+	Message string
+	Sender  string
+	// Ignore says why the event starts no pipeline, when it does not.
+	Ignore string
+	// Number is the pull or merge request's number.
+	Number int
+}
+
+// Provider verifies and parses the deliveries of one git host.
+type Provider interface {
+	// Verify checks the delivery's signature or token against secret.
+	Verify(r *http.Request, body []byte, secret string) error
+	// DeliveryID identifies the delivery; a redelivery has the same one.
+	DeliveryID(r *http.Request) string
+	Parse(r *http.Request, body []byte) (*Event, error)
+}
+
+var providers = map[string]Provider{
+	"github": github{},
+	"gitea":  gitea{},
+	"gitlab": gitlab{},
+}
+
+func Lookup(name string) (Provider, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+var skipPattern = regexp.MustCompile(`(?i)\[(skip ci|ci skip|no ci|skip actions|actions skip)\]`)
+
+// Skipped reports whether the message asks for no pipeline, as with
+// "[skip ci]".
+func Skipped(message string) bool {
+	return skipPattern.MatchString(message)
+}
+
+// verifyHMAC checks a hex HMAC-SHA256 of the body.
+func verifyHMAC(signature string, body []byte, secret string) error {
+	got, err := hex.DecodeString(signature)
+	if err != nil || signature == "" {
+		return ErrBadSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func verifyToken(token, secret string) error {
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// refEvent turns a push to ref into a push or tag event. Deleting a branch
+// or tag starts nothing.
+func refEvent(ref, after, message, sender string) *Event {
+	event := &Event{Kind: Push, Commit: after, Message: message, Sender: sender}
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		event.Ref = strings.TrimPrefix(ref, "refs/heads/")
+	case strings.HasPrefix(ref, "refs/tags/"):
+		event.Kind, event.Ref = Tag, strings.TrimPrefix(ref, "refs/tags/")
+	default:
+		event.Ignore = "unsupported ref " + ref
+	}
+	if strings.Trim(after, "0") == "" {
+		event.Ignore = "ref deleted"
+	}
+	return event
+}
+
+type commit struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// headMessage finds the message of the commit the push ends at.
+func headMessage(head *commit, commits []commit, after string) string {
+	if head != nil && head.ID != "" {
+		return head.Message
+	}
+	for _, c := range commits {
+		if c.ID == after {
+			return c.Message
+		}
+	}
+	return ""
+}
@@ -3,26 +3,60 @@ package ws
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"io/fs"
 	"net/http"
 	"time"
 
 	"nhooyr.io/websocket"
+
+	"openaction/internal/logstream"
 )
 
+var errClosed = errors.New("log stream closed")
+
 type LogStreamer interface {
 	OpenLog(ctx context.Context, logPath string) (io.ReadCloser, error)
 }
 
+// LogHandler streams a step log over a websocket: first the lines already
+// persisted, then live lines from the broker, then an end frame carrying the
+// final step status.
 type LogHandler struct {
-	Open func(ctx context.Context, logPath string) (io.ReadCloser, error)
-	Mask func(line string) string
+	Broker *logstream.Broker
+	Lookup func(ctx context.Context, stepID string) (logPath, status string, err error)
+	Open   func(ctx context.Context, logPath string) (io.ReadCloser, error)
+	Mask   func(line string) string
+}
+
+type lineFrame struct {
+	Type string `json:"type"`
+	Seq  int64  `json:"seq"`
+	Line string `json:"line"`
+}
+
+type endFrame struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+type stream struct {
+	*LogHandler
+	conn   *websocket.Conn
+	stepID string
+	sent   int64
 }
 
 func (h *LogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	logPath := r.URL.Query().Get("path")
-	if logPath == "" {
-		http.Error(w, "missing log path", http.StatusBadRequest)
+	stepID := r.URL.Query().Get("step_id")
+	if stepID == "" {
+		http.Error(w, "missing step_id", http.StatusBadRequest)
+		return
+	}
+	if _, _, err := h.Lookup(r.Context(), stepID); err != nil {
+		http.Error(w, "step not found", http.StatusNotFound)
 		return
 	}
 
@@ -34,22 +68,140 @@ func (h *LogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close(websocket.StatusNormalClosure, "")
 
-	reader, err := h.Open(r.Context(), logPath)
+	s := &stream{LogHandler: h, conn: conn, stepID: stepID}
+	if err := s.run(conn.CloseRead(r.Context())); err != nil && !errors.Is(err, errClosed) {
+		_ = conn.Close(websocket.StatusInternalError, "log stream failed")
+	}
+}
+
+func (s *stream) run(ctx context.Context) error {
+	sub, done, err := s.follow(ctx)
+	if err != nil || done {
+		return err
+	}
+	defer func() {
+		if sub != nil {
+			sub.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return errClosed
+		case event, ok := <-sub.C:
+			switch {
+			case !ok:
+				// Dropped for falling behind: resubscribe and catch up from storage.
+				if sub, done, err = s.follow(ctx); err != nil || done {
+					return err
+				}
+			case event.Done:
+				return s.write(ctx, endFrame{Type: "end", Status: event.Status})
+			case event.Seq < s.sent:
+			case event.Seq > s.sent:
+				sub.Close()
+				if sub, done, err = s.follow(ctx); err != nil || done {
+					return err
+				}
+			default:
+				if err := s.line(ctx, event.Line); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			_, status, err := s.Lookup(ctx, s.stepID)
+			if err != nil {
+				return err
+			}
+			if terminal(status) {
+				sub.Close()
+				_, _, err = s.follow(ctx)
+				return err
+			}
+		}
+	}
+}
+
+// follow subscribes to live lines and then replays the persisted log from
+// the last line sent, so nothing published in between is missed. It reports
+// done once the step has already finished and the end frame was sent.
+func (s *stream) follow(ctx context.Context) (*logstream.Subscription, bool, error) {
+	sub := s.Broker.Subscribe(s.stepID)
+	logPath, status, err := s.Lookup(ctx, s.stepID)
+	if err == nil {
+		err = s.backfill(ctx, logPath)
+	}
+	if err == nil && terminal(status) {
+		err = s.write(ctx, endFrame{Type: "end", Status: status})
+		sub.Close()
+		return nil, true, err
+	}
 	if err != nil {
-		_ = conn.Close(websocket.StatusInternalError, "failed to open log")
-		return
+		sub.Close()
+		return nil, false, err
+	}
+	return sub, false, nil
+}
+
+func (s *stream) backfill(ctx context.Context, logPath string) error {
+	if logPath == "" {
+		return nil
+	}
+	reader, err := s.Open(ctx, logPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Nothing was written yet, or the write that would create it failed.
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 	defer reader.Close()
 
 	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var seq int64
 	for scanner.Scan() {
-		line := scanner.Text()
-		if h.Mask != nil {
-			line = h.Mask(line)
+		if seq >= s.sent {
+			if err := s.line(ctx, scanner.Text()); err != nil {
+				return err
+			}
 		}
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-		_ = conn.Write(ctx, websocket.MessageText, []byte(line))
-		cancel()
-		time.Sleep(30 * time.Millisecond)
+		seq++
+	}
+	return scanner.Err()
+}
+
+func (s *stream) line(ctx context.Context, line string) error {
+	if s.Mask != nil {
+		line = s.Mask(line)
+	}
+	if err := s.write(ctx, lineFrame{Type: "line", Seq: s.sent, Line: line}); err != nil {
+		return err
+	}
+	s.sent++
+	return nil
+}
+
+func (s *stream) write(ctx context.Context, f any) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.conn.Write(writeCtx, websocket.MessageText, data); err != nil {
+		return errClosed
+	}
+	return nil
+}
+
+func terminal(status string) bool {
+	switch status {
+	case "success", "error", "skipped", "cancelled":
+		return true
 	}
+	return false
 }
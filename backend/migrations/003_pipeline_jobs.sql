@@ -0,0 +1,39 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE projects ADD COLUMN pipeline_spec TEXT DEFAULT '';
+
+ALTER TABLE pipelines ADD COLUMN spec TEXT DEFAULT '';
+ALTER TABLE pipelines ADD COLUMN created_at INTEGER;
+
+CREATE TABLE IF NOT EXISTS pipeline_jobs (
+  id TEXT PRIMARY KEY,
+  pipeline_id TEXT NOT NULL,
+  name TEXT NOT NULL,
+  status TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  env_json TEXT NOT NULL DEFAULT '{}',
+  working_directory TEXT NOT NULL DEFAULT '',
+  timeout_seconds INTEGER NOT NULL DEFAULT 0,
+  started_at INTEGER,
+  finished_at INTEGER,
+  FOREIGN KEY(pipeline_id) REFERENCES pipelines(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS pipeline_job_needs (
+  id TEXT PRIMARY KEY,
+  job_id TEXT NOT NULL,
+  needs_job_id TEXT NOT NULL,
+  FOREIGN KEY(job_id) REFERENCES pipeline_jobs(id) ON DELETE CASCADE,
+  FOREIGN KEY(needs_job_id) REFERENCES pipeline_jobs(id) ON DELETE CASCADE
+);
+
+ALTER TABLE pipeline_steps ADD COLUMN job_id TEXT DEFAULT '';
+ALTER TABLE pipeline_steps ADD COLUMN position INTEGER DEFAULT 0;
+ALTER TABLE pipeline_steps ADD COLUMN command TEXT DEFAULT '';
+ALTER TABLE pipeline_steps ADD COLUMN env_json TEXT DEFAULT '{}';
+ALTER TABLE pipeline_steps ADD COLUMN working_directory TEXT DEFAULT '';
+ALTER TABLE pipeline_steps ADD COLUMN timeout_seconds INTEGER DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_pipeline_jobs_pipeline ON pipeline_jobs(pipeline_id);
+CREATE INDEX IF NOT EXISTS idx_pipeline_job_needs_job ON pipeline_job_needs(job_id);
+CREATE INDEX IF NOT EXISTS idx_pipeline_steps_job ON pipeline_steps(job_id);
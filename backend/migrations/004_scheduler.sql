@@ -0,0 +1,8 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipeline_jobs ADD COLUMN runner_id TEXT DEFAULT '';
+ALTER TABLE pipeline_jobs ADD COLUMN lease_id TEXT DEFAULT '';
+ALTER TABLE pipeline_jobs ADD COLUMN lease_expires_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_pipeline_jobs_status ON pipeline_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_pipeline_jobs_lease ON pipeline_jobs(lease_id);
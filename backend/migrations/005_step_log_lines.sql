@@ -0,0 +1 @@
+ALTER TABLE pipeline_steps ADD COLUMN log_lines INTEGER DEFAULT 0;
@@ -0,0 +1,3 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipeline_jobs ADD COLUMN cancel_requested_at INTEGER;
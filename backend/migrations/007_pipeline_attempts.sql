@@ -0,0 +1,8 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipelines ADD COLUMN attempt INTEGER DEFAULT 1;
+ALTER TABLE pipelines ADD COLUMN rerun_of TEXT;
+ALTER TABLE pipelines ADD COLUMN root_id TEXT;
+ALTER TABLE pipeline_jobs ADD COLUMN reused_from TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_root ON pipelines(root_id);
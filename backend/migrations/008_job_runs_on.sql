@@ -0,0 +1,6 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipeline_jobs ADD COLUMN runs_on TEXT DEFAULT '';
+ALTER TABLE pipeline_jobs ADD COLUMN unschedulable INTEGER DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_runner_tags_runner ON runner_tags(runner_id);
@@ -0,0 +1,8 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE runners ADD COLUMN hostname TEXT DEFAULT '';
+ALTER TABLE runners ADD COLUMN os TEXT DEFAULT '';
+ALTER TABLE runners ADD COLUMN arch TEXT DEFAULT '';
+ALTER TABLE runners ADD COLUMN address TEXT DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_runners_status_seen ON runners(status, last_seen);
@@ -0,0 +1,24 @@
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE IF NOT EXISTS runner_registration_tokens (
+  id TEXT PRIMARY KEY,
+  token_hash TEXT NOT NULL UNIQUE,
+  description TEXT NOT NULL DEFAULT '',
+  created_by TEXT NOT NULL DEFAULT '',
+  created_at INTEGER NOT NULL,
+  expires_at INTEGER NOT NULL,
+  used_at INTEGER,
+  runner_id TEXT,
+  revoked_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS runner_credentials (
+  id TEXT PRIMARY KEY,
+  runner_id TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  created_at INTEGER NOT NULL,
+  last_used INTEGER,
+  revoked_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_runner_credentials_runner ON runner_credentials(runner_id);
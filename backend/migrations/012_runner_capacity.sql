@@ -0,0 +1,11 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE runners ADD COLUMN slots INTEGER DEFAULT 1;
+ALTER TABLE runners ADD COLUMN cpu_millis INTEGER DEFAULT 0;
+ALTER TABLE runners ADD COLUMN memory_mb INTEGER DEFAULT 0;
+
+ALTER TABLE pipeline_jobs ADD COLUMN resource_class TEXT DEFAULT '';
+ALTER TABLE pipeline_jobs ADD COLUMN cpu_millis INTEGER DEFAULT 0;
+ALTER TABLE pipeline_jobs ADD COLUMN memory_mb INTEGER DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_pipeline_jobs_runner_status ON pipeline_jobs(runner_id, status);
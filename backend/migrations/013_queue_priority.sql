@@ -0,0 +1,7 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipelines ADD COLUMN priority INTEGER DEFAULT 0;
+ALTER TABLE projects ADD COLUMN share_weight INTEGER DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_status ON pipelines(status);
+CREATE INDEX IF NOT EXISTS idx_pipelines_project_finished ON pipelines(project_id, finished_at);
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipelines ADD COLUMN concurrency_group TEXT DEFAULT '';
+ALTER TABLE pipelines ADD COLUMN cancel_in_progress INTEGER DEFAULT 0;
+ALTER TABLE pipelines ADD COLUMN superseded_by TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_concurrency_group ON pipelines(concurrency_group, status);
@@ -0,0 +1,4 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE runners ADD COLUMN draining INTEGER DEFAULT 0;
+ALTER TABLE runners ADD COLUMN drain_requested_at INTEGER;
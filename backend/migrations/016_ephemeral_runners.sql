@@ -0,0 +1,3 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE runners ADD COLUMN ephemeral INTEGER DEFAULT 0;
@@ -0,0 +1,12 @@
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE IF NOT EXISTS runner_scale_events (
+  id TEXT PRIMARY KEY,
+  group_name TEXT NOT NULL,
+  action TEXT NOT NULL,
+  runner_id TEXT NOT NULL DEFAULT '',
+  reason TEXT NOT NULL DEFAULT '',
+  created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_runner_scale_events_created ON runner_scale_events(created_at);
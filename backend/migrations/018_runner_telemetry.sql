@@ -0,0 +1,22 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE runners ADD COLUMN healthy INTEGER DEFAULT 1;
+ALTER TABLE runners ADD COLUMN unhealthy_reason TEXT DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS runner_telemetry (
+  id TEXT PRIMARY KEY,
+  runner_id TEXT NOT NULL,
+  recorded_at INTEGER NOT NULL,
+  load1 REAL NOT NULL DEFAULT 0,
+  load5 REAL NOT NULL DEFAULT 0,
+  load15 REAL NOT NULL DEFAULT 0,
+  cpus INTEGER NOT NULL DEFAULT 0,
+  memory_total_mb INTEGER NOT NULL DEFAULT 0,
+  memory_available_mb INTEGER NOT NULL DEFAULT 0,
+  disk_total_mb INTEGER NOT NULL DEFAULT 0,
+  disk_free_mb INTEGER NOT NULL DEFAULT 0,
+  running_jobs INTEGER NOT NULL DEFAULT 0,
+  version TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_runner_telemetry_runner ON runner_telemetry(runner_id, recorded_at);
@@ -0,0 +1,19 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipeline_jobs ADD COLUMN checkout TEXT DEFAULT '';
+
+-- Secret names are unique per scope, so every project can have its own
+-- GIT_TOKEN under the scope project:<id>.
+CREATE TABLE secrets_scoped (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  value_enc TEXT NOT NULL,
+  scope TEXT NOT NULL,
+  created_at INTEGER NOT NULL,
+  updated_at INTEGER NOT NULL,
+  UNIQUE(name, scope)
+);
+INSERT INTO secrets_scoped(id,name,value_enc,scope,created_at,updated_at)
+  SELECT id,name,value_enc,scope,created_at,updated_at FROM secrets;
+DROP TABLE secrets;
+ALTER TABLE secrets_scoped RENAME TO secrets;
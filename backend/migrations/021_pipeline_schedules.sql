@@ -0,0 +1,24 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE pipelines ADD COLUMN inputs TEXT DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS pipeline_schedules (
+  id TEXT PRIMARY KEY,
+  project_id TEXT NOT NULL,
+  name TEXT NOT NULL DEFAULT '',
+  cron TEXT NOT NULL,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  branch TEXT NOT NULL,
+  inputs TEXT NOT NULL DEFAULT '{}',
+  enabled INTEGER NOT NULL DEFAULT 1,
+  missed TEXT NOT NULL DEFAULT 'skip',
+  next_run_at INTEGER,
+  last_run_at INTEGER,
+  last_pipeline_id TEXT NOT NULL DEFAULT '',
+  last_error TEXT NOT NULL DEFAULT '',
+  created_at INTEGER NOT NULL,
+  updated_at INTEGER NOT NULL,
+  FOREIGN KEY(project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pipeline_schedules_next ON pipeline_schedules(enabled, next_run_at);
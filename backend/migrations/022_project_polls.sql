@@ -0,0 +1,20 @@
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE IF NOT EXISTS project_polls (
+  project_id TEXT PRIMARY KEY,
+  enabled INTEGER NOT NULL DEFAULT 1,
+  interval_seconds INTEGER NOT NULL DEFAULT 60,
+  branches TEXT NOT NULL DEFAULT '[]',
+  tags TEXT NOT NULL DEFAULT '[]',
+  credentials TEXT NOT NULL DEFAULT '',
+  refs TEXT,
+  failures INTEGER NOT NULL DEFAULT 0,
+  next_poll_at INTEGER,
+  last_polled_at INTEGER,
+  last_success_at INTEGER,
+  last_error TEXT NOT NULL DEFAULT '',
+  updated_at INTEGER NOT NULL,
+  FOREIGN KEY(project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_polls_next ON project_polls(enabled, next_poll_at);
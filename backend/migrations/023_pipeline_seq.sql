@@ -0,0 +1,10 @@
+PRAGMA foreign_keys = ON;
+
+-- seq orders pipelines by creation, also within one second of created_at.
+ALTER TABLE pipelines ADD COLUMN seq INTEGER;
+
+UPDATE pipelines SET seq = ordered.n
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY COALESCE(created_at, 0), rowid) AS n FROM pipelines) AS ordered
+WHERE ordered.id = pipelines.id;
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_seq ON pipelines(seq);
@@ -0,0 +1,4 @@
+PRAGMA foreign_keys = ON;
+
+-- pull_request is the number of the pull request a pipeline was started for.
+ALTER TABLE pipelines ADD COLUMN pull_request INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+PRAGMA foreign_keys = ON;
+
+-- report_session and report_seq are the runner stream session and sequence
+-- number of the last report applied to the job, so a report the runner
+-- sends again after a reconnect or a control plane restart is applied once.
+ALTER TABLE pipeline_jobs ADD COLUMN report_session TEXT;
+ALTER TABLE pipeline_jobs ADD COLUMN report_seq INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_pipeline_jobs_report ON pipeline_jobs(runner_id, report_session);
@@ -0,0 +1,5 @@
+PRAGMA foreign_keys = ON;
+
+-- error is why a job failed before it reached a runner, such as a checkout
+-- whose credentials no longer decrypt.
+ALTER TABLE pipeline_jobs ADD COLUMN error TEXT NOT NULL DEFAULT '';
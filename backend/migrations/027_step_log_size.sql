@@ -0,0 +1,7 @@
+PRAGMA foreign_keys = ON;
+
+-- log_size is the size of the step log file as of the last committed
+-- report. Anything past it was written by a report whose transaction did
+-- not commit and is dropped before the next write. NULL for logs written
+-- before it was tracked, which are appended to as they are.
+ALTER TABLE pipeline_steps ADD COLUMN log_size INTEGER;
@@ -0,0 +1,56 @@
+package jobspec
+
+import "encoding/json"
+
+// Job is the payload handed to a pool runner when it leases a pipeline job.
+type Job struct {
+	ID               string            `json:"id"`
+	LeaseID          string            `json:"lease_id"`
+	PipelineID       string            `json:"pipeline_id"`
+	ProjectID        string            `json:"project_id"`
+	Name             string            `json:"name"`
+	CommitHash       string            `json:"commit_hash"`
+	Branch           string            `json:"branch"`
+	Env              map[string]string `json:"env,omitempty"`
+	WorkingDirectory string            `json:"working_directory,omitempty"`
+	TimeoutSeconds   int64             `json:"timeout_seconds,omitempty"`
+	Checkout         *Checkout         `json:"checkout,omitempty"`
+	Steps            []Step            `json:"steps"`
+}
+
+// Checkout tells the runner to fetch CommitHash from RepoURL into the
+// workspace before the steps run. Jobs without one start in an empty
+// workspace.
+type Checkout struct {
+	RepoURL    string `json:"repo_url"`
+	Depth      int    `json:"depth,omitempty"`
+	Filter     string `json:"filter,omitempty"`
+	Submodules string `json:"submodules,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Token      string `json:"token,omitempty"`
+}
+
+type Step struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Run              string            `json:"run"`
+	Env              map[string]string `json:"env,omitempty"`
+	WorkingDirectory string            `json:"working_directory,omitempty"`
+	TimeoutSeconds   int64             `json:"timeout_seconds,omitempty"`
+}
+
+func Encode(job *Job) (string, error) {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func Decode(payload string) (*Job, error) {
+	var job Job
+	if err := json.Unmarshal([]byte(payload), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
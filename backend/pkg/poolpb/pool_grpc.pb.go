@@ -21,8 +21,10 @@ const _ = grpc.SupportPackageIsVersion8
 const (
 	PoolService_Register_FullMethodName   = "/openaction.pool.v1.PoolService/Register"
 	PoolService_Heartbeat_FullMethodName  = "/openaction.pool.v1.PoolService/Heartbeat"
+	PoolService_Deregister_FullMethodName = "/openaction.pool.v1.PoolService/Deregister"
 	PoolService_FetchJob_FullMethodName   = "/openaction.pool.v1.PoolService/FetchJob"
 	PoolService_ReportStep_FullMethodName = "/openaction.pool.v1.PoolService/ReportStep"
+	PoolService_Connect_FullMethodName    = "/openaction.pool.v1.PoolService/Connect"
 )
 
 // PoolServiceClient is the client API for PoolService service.
@@ -31,8 +33,10 @@ const (
 type PoolServiceClient interface {
 	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
 	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error)
 	FetchJob(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobResponse, error)
 	ReportStep(ctx context.Context, in *StepReport, opts ...grpc.CallOption) (*StepReportResponse, error)
+	Connect(ctx context.Context, opts ...grpc.CallOption) (PoolService_ConnectClient, error)
 }
 
 type poolServiceClient struct {
@@ -63,6 +67,16 @@ func (c *poolServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest,
 	return out, nil
 }
 
+func (c *poolServiceClient) Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeregisterResponse)
+	err := c.cc.Invoke(ctx, PoolService_Deregister_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *poolServiceClient) FetchJob(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(JobResponse)
@@ -83,14 +97,48 @@ func (c *poolServiceClient) ReportStep(ctx context.Context, in *StepReport, opts
 	return out, nil
 }
 
+func (c *poolServiceClient) Connect(ctx context.Context, opts ...grpc.CallOption) (PoolService_ConnectClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PoolService_ServiceDesc.Streams[0], PoolService_Connect_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &poolServiceConnectClient{ClientStream: stream}
+	return x, nil
+}
+
+type PoolService_ConnectClient interface {
+	Send(*RunnerMessage) error
+	Recv() (*ServerMessage, error)
+	grpc.ClientStream
+}
+
+type poolServiceConnectClient struct {
+	grpc.ClientStream
+}
+
+func (x *poolServiceConnectClient) Send(m *RunnerMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *poolServiceConnectClient) Recv() (*ServerMessage, error) {
+	m := new(ServerMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // PoolServiceServer is the server API for PoolService service.
 // All implementations must embed UnimplementedPoolServiceServer
 // for forward compatibility
 type PoolServiceServer interface {
 	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
 	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	Deregister(context.Context, *DeregisterRequest) (*DeregisterResponse, error)
 	FetchJob(context.Context, *JobRequest) (*JobResponse, error)
 	ReportStep(context.Context, *StepReport) (*StepReportResponse, error)
+	Connect(PoolService_ConnectServer) error
 	mustEmbedUnimplementedPoolServiceServer()
 }
 
@@ -104,12 +152,18 @@ func (UnimplementedPoolServiceServer) Register(context.Context, *RegisterRequest
 func (UnimplementedPoolServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
 }
+func (UnimplementedPoolServiceServer) Deregister(context.Context, *DeregisterRequest) (*DeregisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deregister not implemented")
+}
 func (UnimplementedPoolServiceServer) FetchJob(context.Context, *JobRequest) (*JobResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method FetchJob not implemented")
 }
 func (UnimplementedPoolServiceServer) ReportStep(context.Context, *StepReport) (*StepReportResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReportStep not implemented")
 }
+func (UnimplementedPoolServiceServer) Connect(PoolService_ConnectServer) error {
+	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
 func (UnimplementedPoolServiceServer) mustEmbedUnimplementedPoolServiceServer() {}
 
 // UnsafePoolServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -159,6 +213,24 @@ func _PoolService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PoolService_Deregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PoolServiceServer).Deregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PoolService_Deregister_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PoolServiceServer).Deregister(ctx, req.(*DeregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _PoolService_FetchJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(JobRequest)
 	if err := dec(in); err != nil {
@@ -195,6 +267,32 @@ func _PoolService_ReportStep_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PoolService_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PoolServiceServer).Connect(&poolServiceConnectServer{ServerStream: stream})
+}
+
+type PoolService_ConnectServer interface {
+	Send(*ServerMessage) error
+	Recv() (*RunnerMessage, error)
+	grpc.ServerStream
+}
+
+type poolServiceConnectServer struct {
+	grpc.ServerStream
+}
+
+func (x *poolServiceConnectServer) Send(m *ServerMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *poolServiceConnectServer) Recv() (*RunnerMessage, error) {
+	m := new(RunnerMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // PoolService_ServiceDesc is the grpc.ServiceDesc for PoolService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -210,6 +308,10 @@ var PoolService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Heartbeat",
 			Handler:    _PoolService_Heartbeat_Handler,
 		},
+		{
+			MethodName: "Deregister",
+			Handler:    _PoolService_Deregister_Handler,
+		},
 		{
 			MethodName: "FetchJob",
 			Handler:    _PoolService_FetchJob_Handler,
@@ -219,6 +321,13 @@ var PoolService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _PoolService_ReportStep_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       _PoolService_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "pool.proto",
 }
@@ -0,0 +1,53 @@
+// Package quantity parses the CPU and memory amounts used by job resource
+// requests and runner capacity.
+package quantity
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseCPU returns millicores for "2", "1.5" or "500m".
+func ParseCPU(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if milli, ok := strings.CutSuffix(value, "m"); ok {
+		n, err := strconv.Atoi(milli)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid cpu %q", value)
+		}
+		return n, nil
+	}
+	cores, err := strconv.ParseFloat(value, 64)
+	if err != nil || cores <= 0 || math.IsInf(cores, 0) {
+		return 0, fmt.Errorf("invalid cpu %q", value)
+	}
+	return int(math.Ceil(cores * 1000)), nil
+}
+
+var memoryUnits = []struct {
+	suffix string
+	mb     float64
+}{
+	{"Ti", 1024 * 1024}, {"Gi", 1024}, {"Mi", 1},
+	{"T", 1e12 / (1 << 20)}, {"G", 1e9 / (1 << 20)}, {"M", 1e6 / (1 << 20)},
+}
+
+// ParseMemory returns mebibytes for "4Gi", "512Mi", "2G" or a bare number of
+// mebibytes.
+func ParseMemory(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	number, scale := value, 1.0
+	for _, unit := range memoryUnits {
+		if trimmed, ok := strings.CutSuffix(value, unit.suffix); ok {
+			number, scale = trimmed, unit.mb
+			break
+		}
+	}
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount <= 0 || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("invalid memory %q", value)
+	}
+	return int(math.Ceil(amount * scale)), nil
+}
@@ -138,9 +138,9 @@ export const api = {
     >(`/releases/${releaseId}/artifacts`),
 };
 
-export const buildLogStreamUrl = (pipelineId: string, logPath: string) => {
+export const buildLogStreamUrl = (pipelineId: string, stepId: string) => {
   const url = new URL(`/actions/pipelines/${pipelineId}/logs/stream`, window.location.origin);
-  url.searchParams.set('path', logPath);
+  url.searchParams.set('step_id', stepId);
   url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
   return url.toString();
 };
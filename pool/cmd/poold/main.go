@@ -4,76 +4,226 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
+	"openaction-pool/internal/agent"
+	"openaction-pool/internal/workspace"
 	"openaction/pkg/poolpb"
+	"openaction/pkg/quantity"
 )
 
+const version = "0.1.0"
+
 func main() {
+	ephemeral := flag.Bool("ephemeral", envBool("OA_POOL_EPHEMERAL"),
+		"take a single job in a fresh workspace, then deregister and exit")
+	flag.Parse()
+
 	addr := os.Getenv("OA_POOL_ADDR")
 	if addr == "" {
 		addr = "127.0.0.1:7443"
 	}
 
-	tlsConfig, err := loadTLS()
+	workDir := envOr("OA_POOL_WORKDIR", filepath.Join(os.TempDir(), "openaction-pool"))
+	credentialFile := envOr("OA_POOL_CREDENTIAL_FILE", filepath.Join(workDir, "credential.json"))
+	certDir := envOr("OA_POOL_CERT_DIR", filepath.Join(workDir, "certs"))
+	poolID := envOr("OA_POOL_ID", defaultPoolID())
+	if *ephemeral {
+		// Everything an ephemeral runner keeps, its credential included, lives
+		// in a directory of its own that is deleted when it exits, and the
+		// control plane assigns it a fresh id.
+		if err := os.MkdirAll(workDir, 0o755); err != nil {
+			log.Fatalf("workdir error: %v", err)
+		}
+		dir, err := os.MkdirTemp(workDir, "ephemeral-")
+		if err != nil {
+			log.Fatalf("workdir error: %v", err)
+		}
+		workDir, credentialFile, certDir = dir, filepath.Join(dir, "credential.json"), filepath.Join(dir, "certs")
+		poolID = os.Getenv("OA_POOL_ID")
+	}
+	credential, err := agent.LoadCredential(credentialFile)
 	if err != nil {
-		log.Fatalf("tls error: %v", err)
+		log.Fatalf("credential error: %v", err)
+	}
+
+	transport := insecure.NewCredentials()
+	var certs *agent.Certificates
+	if !envBool("OA_POOL_INSECURE") {
+		if os.Getenv("OA_POOL_CERT") == "" {
+			certs, err = agent.LoadCertificates(certDir)
+			if err != nil {
+				log.Fatalf("certificate error: %v", err)
+			}
+		}
+		tlsConfig, err := loadTLS(certs)
+		if err != nil {
+			log.Fatalf("tls error: %v", err)
+		}
+		transport = credentials.NewTLS(tlsConfig)
 	}
 
-	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(transport), grpc.WithPerRPCCredentials(credential))
 	if err != nil {
 		log.Fatalf("grpc dial error: %v", err)
 	}
 	defer conn.Close()
 
-	client := poolpb.NewPoolServiceClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	resp, err := client.Register(ctx, &poolpb.RegisterRequest{
-		Info: &poolpb.PoolInfo{
-			Id:      "pool-dev",
-			Name:    "local-pool",
-			Version: "0.1.0",
-		},
-	})
+	slots, err := strconv.Atoi(envOr("OA_POOL_SLOTS", "1"))
+	if err != nil || slots < 1 {
+		log.Fatalf("invalid OA_POOL_SLOTS %q", os.Getenv("OA_POOL_SLOTS"))
+	}
+	cpuMillis := runtime.NumCPU() * 1000
+	if v := os.Getenv("OA_POOL_CPUS"); v != "" {
+		if cpuMillis, err = quantity.ParseCPU(v); err != nil {
+			log.Fatalf("OA_POOL_CPUS: %v", err)
+		}
+	}
+	memoryMB := agent.HostMemoryMB()
+	if v := os.Getenv("OA_POOL_MEMORY"); v != "" {
+		if memoryMB, err = quantity.ParseMemory(v); err != nil {
+			log.Fatalf("OA_POOL_MEMORY: %v", err)
+		}
+	}
+
+	// An ephemeral runner keeps its workspaces in its own directory too, so
+	// runners sharing a host never clean up each other's jobs.
+	workspaces := &workspace.Manager{Root: workDir, CacheMB: 10 << 10}
+	if !*ephemeral {
+		workspaces.Root = envOr("OA_POOL_WORKSPACE_ROOT", workDir)
+	}
+	if v := os.Getenv("OA_POOL_JOB_DISK_QUOTA"); v != "" {
+		if workspaces.QuotaMB, err = quantity.ParseMemory(v); err != nil {
+			log.Fatalf("OA_POOL_JOB_DISK_QUOTA: %v", err)
+		}
+	}
+	if v := os.Getenv("OA_POOL_CACHE_SIZE"); v != "" {
+		if workspaces.CacheMB, err = quantity.ParseMemory(v); err != nil {
+			log.Fatalf("OA_POOL_CACHE_SIZE: %v", err)
+		}
+	}
+	if err := workspaces.Prepare(); err != nil {
+		log.Fatalf("workspace error: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &agent.Agent{
+		Client:            poolpb.NewPoolServiceClient(conn),
+		PoolID:            poolID,
+		Name:              envOr("OA_POOL_NAME", "local-pool"),
+		Version:           version,
+		Tags:              envList("OA_POOL_TAGS"),
+		Slots:             slots,
+		CPUMillis:         cpuMillis,
+		MemoryMB:          memoryMB,
+		RegistrationToken: os.Getenv("OA_POOL_REGISTRATION_TOKEN"),
+		Credential:        credential,
+		Certificates:      certs,
+		Workspaces:        workspaces,
+		ReconnectInterval: envDuration("OA_POOL_RECONNECT_INTERVAL", 2*time.Second),
+		HeartbeatInterval: envDuration("OA_POOL_HEARTBEAT_INTERVAL", 15*time.Second),
+		ShutdownGrace:     envDuration("OA_POOL_SHUTDOWN_GRACE", 30*time.Second),
+		KillGrace:         envDuration("OA_POOL_KILL_GRACE", 10*time.Second),
+		Ephemeral:         *ephemeral,
+	}
+	err = a.Run(ctx)
+	if *ephemeral {
+		if rmErr := os.RemoveAll(workDir); rmErr != nil {
+			log.Printf("remove workdir: %v", rmErr)
+		}
+	}
 	if err != nil {
-		log.Fatalf("register error: %v", err)
+		log.Fatalf("pool error: %v", err)
 	}
+	log.Printf("pool stopped")
+}
 
-	log.Printf("registered pool: %s", resp.AssignedId)
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envBool(key string) bool {
+	v := os.Getenv(key)
+	return v == "1" || v == "true"
+}
+
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil {
+			return parsed
+		}
+	}
+	return fallback
 }
 
-func loadTLS() (*tls.Config, error) {
+func defaultPoolID() string {
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return "pool-" + host
+	}
+	return "pool-dev"
+}
+
+// loadTLS presents a client certificate, either the one configured or the
+// one issued by the control plane's built-in CA, and checks the server
+// against OA_POOL_CA or the CA bundle received on registration.
+func loadTLS(certs *agent.Certificates) (*tls.Config, error) {
 	certPath := os.Getenv("OA_POOL_CERT")
 	keyPath := os.Getenv("OA_POOL_KEY")
 	caPath := os.Getenv("OA_POOL_CA")
 
-	if certPath == "" || keyPath == "" || caPath == "" {
-		return &tls.Config{InsecureSkipVerify: true}, nil
-	}
-
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
-	if err != nil {
-		return nil, err
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13}
+	if certs != nil {
+		tlsConfig.GetClientCertificate = certs.GetClientCertificate
+		tlsConfig.RootCAs = certs.CAPool()
+	} else if certPath != "" && keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
-	caData, err := os.ReadFile(caPath)
-	if err != nil {
-		return nil, err
+	if caPath != "" {
+		caData, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, err
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("failed to parse %s", caPath)
+		}
+		tlsConfig.RootCAs = caPool
 	}
-	caPool := x509.NewCertPool()
-	if !caPool.AppendCertsFromPEM(caData) {
-		return nil, err
+	if tlsConfig.RootCAs == nil {
+		tlsConfig.InsecureSkipVerify = true
 	}
-
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		RootCAs:      caPool,
-		MinVersion:   tls.VersionTLS13,
-	}, nil
+	return tlsConfig, nil
 }
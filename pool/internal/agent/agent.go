@@ -0,0 +1,511 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"openaction-pool/internal/checkout"
+	"openaction-pool/internal/executor"
+	"openaction-pool/internal/workspace"
+	"openaction/pkg/jobspec"
+	"openaction/pkg/poolpb"
+)
+
+type Agent struct {
+	Client            poolpb.PoolServiceClient
+	PoolID            string
+	Name              string
+	Version           string
+	Tags              []string
+	Slots             int
+	CPUMillis         int
+	MemoryMB          int
+	RegistrationToken string
+	Credential        *Credential
+	Certificates      *Certificates
+	Workspaces        *workspace.Manager
+	ReconnectInterval time.Duration
+	HeartbeatInterval time.Duration
+	ShutdownGrace     time.Duration
+	KillGrace         time.Duration
+	// Ephemeral runners take a single job and deregister once it is done.
+	Ephemeral bool
+
+	sessionID string
+	outbox    *outbox
+	jobs      chan *jobRun
+	idle      chan struct{}
+	drained   chan struct{}
+
+	mu       sync.Mutex
+	running  map[string]*jobRun
+	stopping bool
+	draining bool
+	// spent is set once an ephemeral runner has taken its job.
+	spent bool
+}
+
+// Run registers the pool, opens the job stream and runs the jobs pushed over
+// it, up to Slots at a time, until ctx is cancelled or the runner was
+// drained and has no jobs left. Jobs that are running when ctx ends get
+// ShutdownGrace to finish before their steps are killed and reported as
+// failed; their last reports are flushed before the stream is closed. An
+// ephemeral runner stops after its first job and deregisters.
+func (a *Agent) Run(ctx context.Context) error {
+	if err := a.register(ctx); err != nil {
+		return err
+	}
+	a.sessionID = newSessionID()
+	a.outbox = newOutbox()
+	a.jobs = make(chan *jobRun, a.slots())
+	a.idle = make(chan struct{}, 1)
+	a.drained = make(chan struct{}, 1)
+	a.running = make(map[string]*jobRun)
+
+	if a.Ephemeral {
+		defer a.deregister()
+	}
+	streamCtx, stopStream := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.connect(streamCtx)
+	}()
+	if a.Certificates != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.renewCertificates(streamCtx)
+		}()
+	}
+	defer wg.Wait()
+	defer stopStream()
+
+	var jobs sync.WaitGroup
+	for {
+		select {
+		case <-ctx.Done():
+			a.shutdown(&jobs)
+			return nil
+		case <-a.drained:
+			if a.Ephemeral {
+				log.Printf("ephemeral job done, exiting")
+			} else {
+				log.Printf("runner drained, exiting")
+			}
+			a.shutdown(&jobs)
+			return nil
+		case run := <-a.jobs:
+			jobs.Add(1)
+			go func() {
+				defer jobs.Done()
+				a.runJob(ctx, run)
+				a.mu.Lock()
+				delete(a.running, run.job.ID)
+				a.mu.Unlock()
+				select {
+				case a.idle <- struct{}{}:
+				default:
+				}
+				a.checkDrained()
+			}()
+		}
+	}
+}
+
+// shutdown stops taking jobs, waits for the running ones and flushes their
+// reports.
+func (a *Agent) shutdown(jobs *sync.WaitGroup) {
+	a.mu.Lock()
+	a.stopping = true
+	a.mu.Unlock()
+	jobs.Wait()
+	if !a.outbox.drain(drainTimeout) {
+		log.Printf("shutdown: some step reports were not delivered")
+	}
+}
+
+func (a *Agent) slots() int {
+	if a.Ephemeral {
+		return 1
+	}
+	return max(a.Slots, 1)
+}
+
+func (a *Agent) info() *poolpb.PoolInfo {
+	hostname, _ := os.Hostname()
+	return &poolpb.PoolInfo{
+		Id:       a.PoolID,
+		Name:     a.Name,
+		Version:  a.Version,
+		Tags:     a.Tags,
+		Hostname: hostname,
+		Os:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+
+		Slots:     uint32(a.slots()),
+		CpuMillis: uint32(a.CPUMillis),
+		MemoryMb:  uint64(a.MemoryMB),
+		Ephemeral: a.Ephemeral,
+	}
+}
+
+// register announces the pool to the control plane. A stored credential is
+// used when there is one; otherwise, or when it was revoked, the
+// registration token is redeemed for a new credential.
+func (a *Agent) register(ctx context.Context) error {
+	rpcCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req := &poolpb.RegisterRequest{Info: a.info()}
+	var stored string
+	if a.Credential != nil {
+		var poolID string
+		poolID, stored = a.Credential.get()
+		if stored != "" && poolID != "" {
+			req.Info.Id = poolID
+		}
+	}
+	if stored == "" {
+		req.RegistrationToken = a.RegistrationToken
+	}
+	if a.Certificates != nil && a.Certificates.due() {
+		csr, err := a.Certificates.request(req.Info.Id)
+		if err != nil {
+			return fmt.Errorf("certificate request: %w", err)
+		}
+		req.Csr = csr
+	}
+	resp, err := a.Client.Register(rpcCtx, req)
+	if status.Code(err) == codes.Unauthenticated && stored != "" && a.RegistrationToken != "" {
+		log.Printf("stored credential rejected, registering with token")
+		if err := a.Credential.set("", ""); err != nil {
+			return fmt.Errorf("clear credential: %w", err)
+		}
+		req.RegistrationToken = a.RegistrationToken
+		resp, err = a.Client.Register(rpcCtx, req)
+	}
+	if err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	a.PoolID = resp.AssignedId
+	if resp.Credential != "" {
+		if a.Credential == nil {
+			return fmt.Errorf("register: got a credential but have nowhere to keep it")
+		}
+		if err := a.Credential.set(a.PoolID, resp.Credential); err != nil {
+			return fmt.Errorf("store credential: %w", err)
+		}
+	}
+	if len(resp.Certificate) > 0 {
+		if err := a.Certificates.store(resp.Certificate, resp.CaBundle); err != nil {
+			return fmt.Errorf("store certificate: %w", err)
+		}
+		log.Printf("client certificate issued, valid until %s", a.Certificates.expiry().Format(time.RFC3339))
+	}
+	log.Printf("registered pool: %s", a.PoolID)
+	return nil
+}
+
+// deregister tells the control plane this runner is gone for good, which
+// also revokes its credential.
+func (a *Agent) deregister() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := a.Client.Deregister(ctx, &poolpb.DeregisterRequest{PoolId: a.PoolID}); err != nil {
+		log.Printf("deregister: %v", err)
+		return
+	}
+	if a.Credential != nil {
+		if err := a.Credential.set("", ""); err != nil {
+			log.Printf("clear credential: %v", err)
+		}
+	}
+	log.Printf("deregistered pool: %s", a.PoolID)
+}
+
+// renewCertificates asks for a new client certificate whenever the current
+// one has less than a third of its lifetime left. New connections present it
+// as soon as it is stored.
+func (a *Agent) renewCertificates(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(a.Certificates.checkInterval()):
+		}
+		if !a.Certificates.due() {
+			continue
+		}
+		csr, err := a.Certificates.request(a.PoolID)
+		if err != nil {
+			log.Printf("certificate request: %v", err)
+			continue
+		}
+		rpcCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		resp, err := a.Client.Register(rpcCtx, &poolpb.RegisterRequest{Info: a.info(), Csr: csr})
+		cancel()
+		if err != nil {
+			log.Printf("renew certificate: %v", err)
+			continue
+		}
+		if len(resp.Certificate) == 0 {
+			continue
+		}
+		if err := a.Certificates.store(resp.Certificate, resp.CaBundle); err != nil {
+			log.Printf("store certificate: %v", err)
+			continue
+		}
+		log.Printf("client certificate renewed, valid until %s", a.Certificates.expiry().Format(time.RFC3339))
+	}
+}
+
+type jobRun struct {
+	job       *jobspec.Job
+	ctx       context.Context
+	cancel    context.CancelFunc
+	lost      atomic.Bool
+	cancelled atomic.Bool
+	overQuota atomic.Pointer[workspace.QuotaError]
+}
+
+func newJobRun(job *jobspec.Job) *jobRun {
+	ctx, cancel := context.WithCancel(context.Background())
+	if job.TimeoutSeconds > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, time.Duration(job.TimeoutSeconds)*time.Second)
+		parent := cancel
+		cancel = func() {
+			cancelTimeout()
+			parent()
+		}
+	}
+	return &jobRun{job: job, ctx: ctx, cancel: cancel}
+}
+
+// abandon stops a job this runner no longer owns; nothing more is reported
+// for it.
+func (r *jobRun) abandon() {
+	r.lost.Store(true)
+	r.cancel()
+}
+
+// stop terminates the running step because the pipeline was cancelled.
+func (r *jobRun) stop() {
+	r.cancelled.Store(true)
+	r.cancel()
+}
+
+func (a *Agent) runJob(ctx context.Context, run *jobRun) {
+	job := run.job
+	log.Printf("job %s (%s): started", job.ID, job.Name)
+	defer run.cancel()
+
+	go func() {
+		select {
+		case <-run.ctx.Done():
+		case <-ctx.Done():
+			log.Printf("job %s: shutdown requested, waiting up to %s", job.ID, a.ShutdownGrace)
+			select {
+			case <-run.ctx.Done():
+			case <-time.After(a.ShutdownGrace):
+				run.cancel()
+			}
+		}
+	}()
+
+	workspace, err := a.Workspaces.Create(job.ID)
+	if err != nil {
+		a.finish(run, job.Steps[0].Name, "error", "cannot create workspace: "+err.Error())
+		return
+	}
+	defer a.Workspaces.Remove(job.ID)
+	if a.Workspaces.QuotaMB > 0 {
+		go a.watchQuota(run, workspace)
+	}
+	if job.Checkout != nil && !a.checkout(run, workspace) {
+		return
+	}
+
+	for _, step := range job.Steps {
+		if !a.runStep(ctx, run, workspace, step) {
+			break
+		}
+	}
+	log.Printf("job %s (%s): finished", job.ID, job.Name)
+}
+
+// checkout fetches the job's commit into the workspace. Its output is
+// logged under the first step, which fails if the checkout does.
+func (a *Agent) checkout(run *jobRun, workspace string) bool {
+	job := run.job
+	first := job.Steps[0].Name
+	if !a.event(run, first, "running") {
+		return false
+	}
+	err := checkout.Run(run.ctx, a.Workspaces, workspace, job.CommitHash, job.Checkout, func(line string) {
+		a.outbox.log(run.ctx, job.ID, job.LeaseID, first, line)
+	})
+	switch {
+	case run.lost.Load():
+		return false
+	case run.cancelled.Load():
+		return a.finish(run, first, "cancelled", "step cancelled")
+	case err != nil:
+		return a.finish(run, first, "error", "checkout failed: "+err.Error())
+	}
+	return true
+}
+
+// watchQuota stops the job once its workspace outgrows the disk quota.
+func (a *Agent) watchQuota(run *jobRun, dir string) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-run.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		a.checkQuota(run, dir)
+		if run.overQuota.Load() != nil {
+			return
+		}
+	}
+}
+
+func (a *Agent) checkQuota(run *jobRun, dir string) {
+	var exceeded *workspace.QuotaError
+	if err := a.Workspaces.CheckQuota(dir); errors.As(err, &exceeded) {
+		log.Printf("job %s: %v", run.job.ID, exceeded)
+		run.overQuota.Store(exceeded)
+		run.cancel()
+	}
+}
+
+func (a *Agent) runStep(ctx context.Context, run *jobRun, workspace string, step jobspec.Step) bool {
+	job := run.job
+	if !a.event(run, step.Name, "running") {
+		return false
+	}
+	dir := filepath.Join(workspace, job.WorkingDirectory, step.WorkingDirectory)
+	if rel, err := filepath.Rel(workspace, dir); err != nil || !filepath.IsLocal(rel) {
+		return a.finish(run, step.Name, "error", "working directory is outside the workspace")
+	}
+	result := executor.Run(run.ctx, executor.Step{
+		Command:   step.Run,
+		Dir:       dir,
+		Env:       stepEnv(job, step, workspace),
+		Timeout:   time.Duration(step.TimeoutSeconds) * time.Second,
+		KillGrace: a.KillGrace,
+	}, func(line string) {
+		a.outbox.log(run.ctx, job.ID, job.LeaseID, step.Name, line)
+	})
+
+	if result.Err == nil && run.overQuota.Load() == nil {
+		a.checkQuota(run, workspace)
+	}
+	switch {
+	case run.lost.Load():
+		return false
+	case run.cancelled.Load():
+		return a.finish(run, step.Name, "cancelled", "step cancelled")
+	case run.overQuota.Load() != nil:
+		return a.finish(run, step.Name, "error", run.overQuota.Load().Error())
+	case result.Err != nil:
+		return a.finish(run, step.Name, "error", "failed to start step: "+result.Err.Error())
+	case result.TimedOut:
+		return a.finish(run, step.Name, "error", fmt.Sprintf("step timed out after %ds", step.TimeoutSeconds))
+	case run.ctx.Err() != nil && ctx.Err() != nil:
+		return a.finish(run, step.Name, "error", "runner shut down before the step finished")
+	case run.ctx.Err() != nil:
+		return a.finish(run, step.Name, "error", fmt.Sprintf("job timed out after %ds", job.TimeoutSeconds))
+	case result.ExitCode != 0:
+		return a.finish(run, step.Name, "error", fmt.Sprintf("process exited with code %d", result.ExitCode))
+	}
+	return a.finish(run, step.Name, "success", "")
+}
+
+func (a *Agent) finish(run *jobRun, stepName, stepStatus, line string) bool {
+	if line != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		a.outbox.log(ctx, run.job.ID, run.job.LeaseID, stepName, line)
+		cancel()
+	}
+	ok := a.event(run, stepName, stepStatus)
+	return ok && stepStatus == "success"
+}
+
+// event queues a step status change. Once the server has cancelled the job
+// nothing more is sent for it.
+func (a *Agent) event(run *jobRun, stepName, stepStatus string) bool {
+	if run.lost.Load() {
+		return false
+	}
+	a.outbox.step(&poolpb.StepEvent{
+		JobId:     run.job.ID,
+		LeaseId:   run.job.LeaseID,
+		StepName:  stepName,
+		Status:    stepStatus,
+		Timestamp: time.Now().Unix(),
+	})
+	return true
+}
+
+func newSessionID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// stepEnv builds a step's environment. poold's own OA_POOL_* settings, the
+// registration token among them, are dropped so build scripts cannot read
+// them.
+func stepEnv(job *jobspec.Job, step jobspec.Step, workspace string) []string {
+	env := append(hostEnv(),
+		"CI=true",
+		"OPENACTION=true",
+		"OA_WORKSPACE="+workspace,
+		"OA_PIPELINE_ID="+job.PipelineID,
+		"OA_PROJECT_ID="+job.ProjectID,
+		"OA_JOB_ID="+job.ID,
+		"OA_JOB_NAME="+job.Name,
+		"OA_STEP_NAME="+step.Name,
+		"OA_COMMIT_HASH="+job.CommitHash,
+		"OA_BRANCH="+job.Branch,
+	)
+	for key, value := range job.Env {
+		env = append(env, key+"="+value)
+	}
+	for key, value := range step.Env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// hostEnv returns poold's environment without its OA_POOL_* settings.
+func hostEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "OA_POOL_") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Certificates holds the client certificate issued by the control plane's
+// built-in CA. A new key and request are made for every renewal; the key is
+// only written next to the certificate once the certificate arrives.
+type Certificates struct {
+	dir string
+
+	mu      sync.Mutex
+	current *tls.Certificate
+	pending *ecdsa.PrivateKey
+	ca      []byte
+}
+
+// LoadCertificates reads the certificate, key and CA bundle kept in dir, if
+// there are any.
+func LoadCertificates(dir string) (*Certificates, error) {
+	c := &Certificates{dir: dir}
+	ca, err := os.ReadFile(filepath.Join(dir, "ca.crt"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	c.ca = ca
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+	c.current = &cert
+	return c, nil
+}
+
+// CAPool returns the stored CA bundle, or nil when there is none yet.
+func (c *Certificates) CAPool() *x509.CertPool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.ca) == 0 {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(c.ca) {
+		return nil
+	}
+	return pool
+}
+
+// due reports whether there is no certificate or it has less than a third of
+// its lifetime left.
+func (c *Certificates) due() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil {
+		return true
+	}
+	leaf := c.current.Leaf
+	return time.Until(leaf.NotAfter) < leaf.NotAfter.Sub(leaf.NotBefore)/3
+}
+
+// request makes a new key and returns a PEM certificate request for it.
+func (c *Certificates) request(poolID string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: poolID},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.pending = key
+	c.mu.Unlock()
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// store pairs a certificate issued for the last request with its key and
+// keeps both, along with the CA bundle.
+func (c *Certificates) store(certPEM, caPEM []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending == nil {
+		return fmt.Errorf("no certificate request outstanding")
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.pending)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, "client.key"), keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, "client.crt"), certPEM, 0o644); err != nil {
+		return err
+	}
+	if len(caPEM) > 0 {
+		if err := os.WriteFile(filepath.Join(c.dir, "ca.crt"), caPEM, 0o644); err != nil {
+			return err
+		}
+		c.ca = caPEM
+	}
+	c.current = &cert
+	c.pending = nil
+	return nil
+}
+
+// checkInterval is how often to look at whether renewal is due: a tenth of
+// the certificate's lifetime, at most an hour.
+func (c *Certificates) checkInterval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil {
+		return time.Minute
+	}
+	interval := c.current.Leaf.NotAfter.Sub(c.current.Leaf.NotBefore) / 10
+	if interval > time.Hour {
+		return time.Hour
+	}
+	return max(interval, time.Second)
+}
+
+func (c *Certificates) expiry() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil {
+		return time.Time{}
+	}
+	return c.current.Leaf.NotAfter
+}
+
+// GetClientCertificate presents the current certificate, or none before the
+// first one has been issued.
+func (c *Certificates) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil {
+		return &tls.Certificate{}, nil
+	}
+	return c.current, nil
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Credential is the runner credential issued when a registration token is
+// redeemed. It is kept in a file so the runner stays registered across
+// restarts, and is attached to every call as a bearer token.
+type Credential struct {
+	path string
+
+	mu     sync.Mutex
+	poolID string
+	token  string
+}
+
+type credentialFile struct {
+	PoolID     string `json:"pool_id"`
+	Credential string `json:"credential"`
+}
+
+// LoadCredential reads the credential stored at path. A missing file gives
+// an empty credential.
+func LoadCredential(path string) (*Credential, error) {
+	c := &Credential{path: path}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored credentialFile
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+	c.poolID, c.token = stored.PoolID, stored.Credential
+	return c, nil
+}
+
+func (c *Credential) get() (poolID, token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.poolID, c.token
+}
+
+func (c *Credential) set(poolID, token string) error {
+	c.mu.Lock()
+	c.poolID, c.token = poolID, token
+	c.mu.Unlock()
+	if token == "" {
+		err := os.Remove(c.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	raw, err := json.Marshal(credentialFile{PoolID: poolID, Credential: token})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, raw, 0o600)
+}
+
+func (c *Credential) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if _, token := c.get(); token != "" {
+		return map[string]string{"authorization": "Bearer " + token}, nil
+	}
+	return nil, nil
+}
+
+// RequireTransportSecurity is false so runners can also talk to a control
+// plane that serves gRPC without TLS.
+func (c *Credential) RequireTransportSecurity() bool {
+	return false
+}
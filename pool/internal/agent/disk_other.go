@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package agent
+
+func diskUsage(dir string) (totalMB, freeMB uint64) {
+	return 0, 0
+}
@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage measures the filesystem holding dir, which is created first so
+// a runner that has not run a job yet still reports.
+func diskUsage(dir string) (totalMB, freeMB uint64) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, 0
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, 0
+	}
+	bsize := uint64(st.Bsize)
+	return st.Blocks * bsize >> 20, st.Bavail * bsize >> 20
+}
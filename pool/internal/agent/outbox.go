@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"openaction/pkg/poolpb"
+)
+
+const (
+	maxPending    = 1000
+	maxChunkLines = 500
+	flushInterval = 200 * time.Millisecond
+	drainTimeout  = 10 * time.Second
+)
+
+// outbox holds step events and log chunks until the server acks them, so
+// they survive a reconnect. Lines logged for a step are merged into the
+// last chunk while it has not been sent yet, which batches fast output.
+type outbox struct {
+	mu      sync.Mutex
+	pending []*poolpb.RunnerMessage
+	sent    int
+	nextSeq uint64
+	notify  chan struct{}
+	space   chan struct{}
+}
+
+func newOutbox() *outbox {
+	return &outbox{notify: make(chan struct{}, 1), space: make(chan struct{})}
+}
+
+func (o *outbox) step(event *poolpb.StepEvent) {
+	o.mu.Lock()
+	o.append(&poolpb.RunnerMessage{Body: &poolpb.RunnerMessage_Step{Step: event}})
+	o.mu.Unlock()
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// log queues a line for the step. It blocks while the outbox is full, which
+// in turn holds back the step's output, and gives up when ctx ends.
+func (o *outbox) log(ctx context.Context, jobID, leaseID, stepName, line string) bool {
+	for {
+		o.mu.Lock()
+		if len(o.pending) < maxPending {
+			break
+		}
+		space := o.space
+		o.mu.Unlock()
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	defer o.mu.Unlock()
+
+	if n := len(o.pending); n > o.sent {
+		if chunk := o.pending[n-1].GetLogs(); chunk != nil && chunk.JobId == jobID &&
+			chunk.StepName == stepName && len(chunk.Lines) < maxChunkLines {
+			chunk.Lines = append(chunk.Lines, line)
+			return true
+		}
+	}
+	o.append(&poolpb.RunnerMessage{Body: &poolpb.RunnerMessage_Logs{Logs: &poolpb.LogChunk{
+		JobId:    jobID,
+		LeaseId:  leaseID,
+		StepName: stepName,
+		Lines:    []string{line},
+	}}})
+	return true
+}
+
+func (o *outbox) append(msg *poolpb.RunnerMessage) {
+	o.nextSeq++
+	msg.Seq = o.nextSeq
+	o.pending = append(o.pending, msg)
+}
+
+// unsent returns the messages not yet written to the current stream and
+// marks them as sent.
+func (o *outbox) unsent() []*poolpb.RunnerMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	msgs := append([]*poolpb.RunnerMessage(nil), o.pending[o.sent:]...)
+	o.sent = len(o.pending)
+	return msgs
+}
+
+func (o *outbox) ack(seq uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := 0
+	for n < len(o.pending) && o.pending[n].Seq <= seq {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	o.pending = append([]*poolpb.RunnerMessage(nil), o.pending[n:]...)
+	o.sent = max(o.sent-n, 0)
+	close(o.space)
+	o.space = make(chan struct{})
+}
+
+// jobs returns the IDs of jobs with unacked messages.
+func (o *outbox) jobs() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	seen := make(map[string]bool)
+	var ids []string
+	for _, msg := range o.pending {
+		id := msg.GetStep().GetJobId()
+		if id == "" {
+			id = msg.GetLogs().GetJobId()
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// rewind makes every unacked message eligible to be sent again, for use
+// after a reconnect.
+func (o *outbox) rewind() {
+	o.mu.Lock()
+	o.sent = 0
+	o.mu.Unlock()
+}
+
+// drain waits until everything was acked or the timeout passes.
+func (o *outbox) drain(timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		o.mu.Lock()
+		empty := len(o.pending) == 0
+		space := o.space
+		o.mu.Unlock()
+		if empty {
+			return true
+		}
+		select {
+		case <-space:
+		case <-deadline:
+			return false
+		}
+	}
+}
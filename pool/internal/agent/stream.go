@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"openaction/pkg/jobspec"
+	"openaction/pkg/poolpb"
+)
+
+const maxReconnectInterval = 30 * time.Second
+
+// connect keeps a job stream open until ctx ends, reconnecting with
+// backoff when it drops.
+func (a *Agent) connect(ctx context.Context) {
+	backoff := a.ReconnectInterval
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := a.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = a.ReconnectInterval
+		}
+		log.Printf("job stream disconnected: %v (retrying in %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxReconnectInterval)
+	}
+}
+
+func (a *Agent) session(ctx context.Context) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := a.Client.Connect(streamCtx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.CloseSend() }()
+
+	if err := stream.Send(&poolpb.RunnerMessage{Body: &poolpb.RunnerMessage_Hello{Hello: &poolpb.Hello{
+		Info:        a.info(),
+		SessionId:   a.sessionID,
+		RunningJobs: a.runningJobs(),
+	}}}); err != nil {
+		return err
+	}
+
+	inbox := make(chan *poolpb.ServerMessage)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case inbox <- msg:
+			case <-streamCtx.Done():
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(a.HeartbeatInterval)
+	defer heartbeat.Stop()
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+
+	// granted counts credits sent on this stream that no job has used yet.
+	welcomed, granted := false, 0
+	for {
+		if free := a.freeSlots() - granted; welcomed && free > 0 {
+			if err := stream.Send(&poolpb.RunnerMessage{Body: &poolpb.RunnerMessage_Credit{Credit: &poolpb.JobCredit{Jobs: uint32(free)}}}); err != nil {
+				return err
+			}
+			granted += free
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		case msg := <-inbox:
+			switch body := msg.Body.(type) {
+			case *poolpb.ServerMessage_Welcome:
+				a.outbox.ack(body.Welcome.LastSeq)
+				a.outbox.rewind()
+				welcomed = true
+				log.Printf("job stream connected")
+			case *poolpb.ServerMessage_Ack:
+				a.outbox.ack(body.Ack.Seq)
+			case *poolpb.ServerMessage_Job:
+				granted = max(granted-1, 0)
+				a.assign(body.Job)
+			case *poolpb.ServerMessage_Cancel:
+				a.cancelJob(body.Cancel)
+			case *poolpb.ServerMessage_Drain:
+				a.setDraining(body.Drain.Draining)
+			}
+			continue
+		case <-a.idle:
+			continue
+		case <-heartbeat.C:
+			if err := stream.Send(&poolpb.RunnerMessage{Body: &poolpb.RunnerMessage_Heartbeat{Heartbeat: &poolpb.HeartbeatRequest{
+				PoolId:    a.PoolID,
+				Timestamp: time.Now().Unix(),
+				Telemetry: a.telemetry(),
+			}}}); err != nil {
+				return err
+			}
+		case <-a.outbox.notify:
+		case <-flush.C:
+		}
+
+		if !welcomed {
+			continue
+		}
+		for _, msg := range a.outbox.unsent() {
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (a *Agent) assign(assignment *poolpb.JobAssignment) {
+	job, err := jobspec.Decode(assignment.Payload)
+	if err != nil {
+		log.Printf("job %s: invalid assignment: %v", assignment.JobId, err)
+		return
+	}
+	run := newJobRun(job)
+	a.mu.Lock()
+	a.running[job.ID] = run
+	a.mu.Unlock()
+	select {
+	case a.jobs <- run:
+		if a.Ephemeral {
+			a.mu.Lock()
+			a.spent = true
+			a.mu.Unlock()
+		}
+	default:
+		a.mu.Lock()
+		delete(a.running, job.ID)
+		a.mu.Unlock()
+		log.Printf("job %s: assigned while busy, leaving it to expire", job.ID)
+	}
+}
+
+func (a *Agent) cancelJob(cancel *poolpb.CancelJob) {
+	a.mu.Lock()
+	run := a.running[cancel.JobId]
+	a.mu.Unlock()
+	if run == nil || (cancel.LeaseId != "" && cancel.LeaseId != run.job.LeaseID) {
+		return
+	}
+	log.Printf("job %s: cancelled by server: %s", cancel.JobId, cancel.Reason)
+	if cancel.LeaseLost {
+		run.abandon()
+		return
+	}
+	run.stop()
+}
+
+// runningJobs lists the jobs the server should keep leased to this runner:
+// the ones running and the ones whose final reports are still unacked.
+func (a *Agent) runningJobs() []string {
+	ids := a.outbox.jobs()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id := range a.running {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// freeSlots is how many more jobs the runner can take right now.
+func (a *Agent) freeSlots() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopping || a.draining || a.spent {
+		return 0
+	}
+	return max(a.slots()-len(a.running), 0)
+}
+
+// setDraining stops or resumes taking jobs as the server says. A drained
+// runner exits once its jobs are done.
+func (a *Agent) setDraining(draining bool) {
+	a.mu.Lock()
+	changed := a.draining != draining
+	a.draining = draining
+	running := len(a.running)
+	a.mu.Unlock()
+	if !changed {
+		return
+	}
+	if draining {
+		log.Printf("runner drained by the server, finishing %d running jobs", running)
+		a.checkDrained()
+	} else {
+		log.Printf("runner resumed by the server")
+	}
+}
+
+// checkDrained signals Run to exit once a drained runner, or an ephemeral
+// one that has taken its job, has nothing left running.
+func (a *Agent) checkDrained() {
+	a.mu.Lock()
+	done := (a.draining || a.spent) && len(a.running) == 0
+	a.mu.Unlock()
+	if !done {
+		return
+	}
+	select {
+	case a.drained <- struct{}{}:
+	default:
+	}
+}
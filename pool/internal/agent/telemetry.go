@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"openaction/pkg/poolpb"
+)
+
+// telemetry samples the host for the next heartbeat. Whatever cannot be
+// read on this platform is left at zero.
+func (a *Agent) telemetry() *poolpb.Telemetry {
+	t := &poolpb.Telemetry{
+		Cpus:    uint32(runtime.NumCPU()),
+		Version: a.Version,
+	}
+	t.Load1, t.Load5, t.Load15 = loadAverage()
+	total, available := memInfo()
+	t.MemoryTotalMb, t.MemoryAvailableMb = uint64(total), uint64(available)
+	t.DiskTotalMb, t.DiskFreeMb = diskUsage(a.Workspaces.Root)
+	a.mu.Lock()
+	t.RunningJobs = uint32(len(a.running))
+	a.mu.Unlock()
+	return t
+}
+
+// HostMemoryMB is the host's total memory, or zero where it cannot be read.
+func HostMemoryMB() int {
+	total, _ := memInfo()
+	return total
+}
+
+// memInfo reads total and available memory from /proc/meminfo.
+func memInfo() (totalMB, availableMB int) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			totalMB = kb / 1024
+		case "MemAvailable:":
+			availableMB = kb / 1024
+		}
+	}
+	return totalMB, availableMB
+}
+
+func loadAverage() (load1, load5, load15 float64) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, 0, 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return 0, 0, 0
+	}
+	load1, _ = strconv.ParseFloat(fields[0], 64)
+	load5, _ = strconv.ParseFloat(fields[1], 64)
+	load15, _ = strconv.ParseFloat(fields[2], 64)
+	return load1, load5, load15
+}
@@ -0,0 +1,218 @@
+package checkout
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"openaction-pool/internal/workspace"
+	"openaction/pkg/jobspec"
+)
+
+// Run checks out commit from the repository in c into dir. Objects come
+// from a bare mirror kept in the workspace cache, which only goes to the
+// network when it does not have the commit yet, so jobs of the same
+// repository share one clone. Every git command and its output is passed to
+// logf.
+func Run(ctx context.Context, workspaces *workspace.Manager, dir, commit string, c *jobspec.Checkout, logf func(string)) error {
+	if !commitPattern.MatchString(commit) {
+		return fmt.Errorf("invalid commit %q", commit)
+	}
+	g := &git{ctx: ctx, env: credentialEnv(c), logf: logf}
+	if err := g.fromMirror(workspaces, dir, commit, c); err != nil {
+		return err
+	}
+
+	if c.Submodules == "" {
+		return nil
+	}
+	// Submodules are fetched straight from their repositories. file://
+	// submodules are only allowed in a file:// repository, where local test
+	// repositories need them; elsewhere a .gitmodules could reach into the
+	// runner's own disk.
+	update := []string{"-C", dir, "submodule", "update", "-q", "--init"}
+	if strings.HasPrefix(c.RepoURL, "file://") {
+		update = append([]string{"-c", "protocol.file.allow=always"}, update...)
+	}
+	if c.Submodules == "recursive" {
+		update = append(update, "--recursive")
+	}
+	if c.Depth > 0 {
+		update = append(update, "--depth", strconv.Itoa(c.Depth))
+	}
+	return g.run(update...)
+}
+
+// fromMirror checks the commit out of the mirror, holding it so that no
+// other job updates or evicts it meanwhile.
+func (g *git) fromMirror(workspaces *workspace.Manager, dir, commit string, c *jobspec.Checkout) error {
+	mirror, release, err := workspaces.Acquire(c.RepoURL)
+	if err != nil {
+		return err
+	}
+	defer release()
+	sha, err := g.updateMirror(mirror, commit, c)
+	if err != nil {
+		return err
+	}
+
+	fetch := []string{"-C", dir, "fetch", "-q", "--no-tags"}
+	if c.Depth > 0 {
+		fetch = append(fetch, "--depth", strconv.Itoa(c.Depth))
+	}
+	if c.Filter != "" {
+		fetch = append(fetch, "--filter", c.Filter)
+	}
+	steps := [][]string{
+		{"init", "-q", dir},
+		{"-C", dir, "remote", "add", "origin", mirrorURL(mirror)},
+		append(fetch, "origin", sha),
+		{"-C", dir, "checkout", "-q", "--detach", sha},
+		// Later fetches by the steps go to the repository, not the cache.
+		{"-C", dir, "remote", "set-url", "--", "origin", c.RepoURL},
+	}
+	for _, args := range steps {
+		if err := g.run(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMirror makes sure the mirror has commit and returns its full hash.
+// A mirror that was never cloned successfully is cloned again, as is a
+// partial one: the mirror is shared, so it always holds every object and
+// only the workspace fetch from it is filtered.
+func (g *git) updateMirror(mirror, commit string, c *jobspec.Checkout) (string, error) {
+	if _, err := os.Stat(filepath.Join(mirror, "HEAD")); err != nil || g.partial(mirror) {
+		if err := os.RemoveAll(mirror); err != nil {
+			return "", err
+		}
+		if err := g.run("clone", "-q", "--mirror", "--", c.RepoURL, mirror); err != nil {
+			return "", err
+		}
+		for _, setting := range []string{"uploadpack.allowFilter", "uploadpack.allowAnySHA1InWant"} {
+			if err := g.run("-C", mirror, "config", setting, "true"); err != nil {
+				return "", err
+			}
+		}
+	}
+	if sha, err := g.resolve(mirror, commit); err == nil {
+		return sha, nil
+	}
+	if err := g.run("-C", mirror, "fetch", "-q", "--prune", "origin"); err != nil {
+		return "", err
+	}
+	if sha, err := g.resolve(mirror, commit); err == nil {
+		return sha, nil
+	}
+	// The commit is not on any branch or tag; servers that allow it can
+	// still hand it out by hash.
+	if err := g.run("-C", mirror, "fetch", "-q", "origin", "--", commit); err != nil {
+		return "", fmt.Errorf("commit %s not found in %s", commit, c.RepoURL)
+	}
+	return g.resolve(mirror, commit)
+}
+
+// partial reports whether repo is a partial clone, missing objects it would
+// fetch on demand.
+func (g *git) partial(repo string) bool {
+	cmd := exec.CommandContext(g.ctx, "git", "-C", repo, "config", "--get", "remote.origin.promisor")
+	cmd.Env = g.env
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+func (g *git) resolve(repo, commit string) (string, error) {
+	cmd := exec.CommandContext(g.ctx, "git", "-C", repo, "rev-parse", "-q", "--verify", commit+"^{commit}")
+	cmd.Env = g.env
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// commitPattern is what Run accepts as a commit, a hash. Anything else could
+// be taken for a git option.
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+
+type git struct {
+	ctx  context.Context
+	env  []string
+	logf func(string)
+}
+
+func (g *git) run(args ...string) error {
+	g.logf("$ git " + strings.Join(args, " "))
+	cmd := exec.CommandContext(g.ctx, "git", args...)
+	cmd.Env = g.env
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+	last := ""
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			g.logf(line)
+			last = line
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if last != "" {
+		return fmt.Errorf("git %s: %s", args[commandIndex(args)], last)
+	}
+	return fmt.Errorf("git %s: %w", args[commandIndex(args)], err)
+}
+
+// commandIndex finds the git subcommand after the -C and -c options.
+func commandIndex(args []string) int {
+	i := 0
+	for i+1 < len(args) && (args[i] == "-C" || args[i] == "-c") {
+		i += 2
+	}
+	return i
+}
+
+// credentialEnv is the environment git runs in. The token is sent as a
+// basic auth header to the repository's host only, through GIT_CONFIG_*
+// so that it never shows up in a command line or the mirror's config.
+func credentialEnv(c *jobspec.Checkout) []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if c.Token == "" {
+		return env
+	}
+	u, err := url.Parse(c.RepoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return env
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Token))
+	return append(env,
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http."+u.Scheme+"://"+u.Host+"/.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+	)
+}
+
+func mirrorURL(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+const maxLineSize = 1024 * 1024
+
+const defaultKillGrace = 10 * time.Second
+
+type Step struct {
+	Command string
+	Dir     string
+	Env     []string
+	Timeout time.Duration
+	// KillGrace is how long the process group gets to exit after SIGTERM
+	// before it is killed.
+	KillGrace time.Duration
+}
+
+type Result struct {
+	ExitCode int
+	TimedOut bool
+	Err      error
+}
+
+// Run executes the step through the platform shell and calls output for
+// every line written to stdout or stderr. It blocks until the process exits
+// and all output has been delivered. When ctx ends the step's process group
+// is terminated, then killed if it outlives KillGrace.
+func Run(ctx context.Context, step Step, output func(line string)) Result {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+
+	if err := os.MkdirAll(step.Dir, 0o755); err != nil {
+		return Result{ExitCode: -1, Err: err}
+	}
+
+	grace := step.KillGrace
+	if grace <= 0 {
+		grace = defaultKillGrace
+	}
+	cmd := shellCommand(ctx, step.Command)
+	cmd.Dir = step.Dir
+	cmd.Env = step.Env
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		// The group is killed once the grace period is over even if the shell
+		// exits sooner, since what it started may ignore SIGTERM.
+		time.AfterFunc(grace, func() { killProcessGroup(cmd) })
+		return terminateProcessGroup(cmd)
+	}
+	cmd.WaitDelay = grace + 5*time.Second
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		for scanner.Scan() {
+			output(scanner.Text())
+		}
+		_, _ = io.Copy(io.Discard, reader)
+	}()
+
+	err := cmd.Run()
+	_ = writer.Close()
+	<-done
+
+	result := Result{ExitCode: cmd.ProcessState.ExitCode()}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		result.Err = err
+	}
+	return result
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
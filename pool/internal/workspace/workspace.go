@@ -0,0 +1,204 @@
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manager owns the directories jobs run in: one per job under Root/jobs,
+// removed when the job ends, and a cache of reusable checkouts under
+// Root/cache that is evicted least recently used first once it outgrows
+// CacheMB.
+type Manager struct {
+	Root string
+	// QuotaMB caps the disk a job's workspace may use; zero means no cap.
+	QuotaMB int
+	// CacheMB caps the checkout cache; zero means it is not kept between
+	// jobs.
+	CacheMB int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	dir      string
+	lastUsed time.Time
+	size     int64
+	users    int
+	lock     sync.Mutex
+}
+
+// QuotaError is returned when a workspace uses more disk than its quota.
+type QuotaError struct {
+	UsedMB  int64
+	QuotaMB int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("workspace disk quota exceeded: %d MB used, quota is %d MB", e.UsedMB, e.QuotaMB)
+}
+
+func (m *Manager) jobsDir() string  { return filepath.Join(m.Root, "jobs") }
+func (m *Manager) cacheDir() string { return filepath.Join(m.Root, "cache") }
+
+// Prepare creates the directories, removes job workspaces left behind by a
+// runner that did not shut down cleanly and picks up the cached checkouts.
+func (m *Manager) Prepare() error {
+	if err := os.RemoveAll(m.jobsDir()); err != nil {
+		return err
+	}
+	for _, dir := range []string{m.jobsDir(), m.cacheDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	dirs, err := os.ReadDir(m.cacheDir())
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[string]*entry)
+	for _, d := range dirs {
+		info, err := d.Info()
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(m.cacheDir(), d.Name())
+		size, _ := Usage(dir)
+		m.entries[d.Name()] = &entry{dir: dir, lastUsed: info.ModTime(), size: size}
+	}
+	m.evictLocked()
+	return nil
+}
+
+// Create makes an empty workspace for the job.
+func (m *Manager) Create(jobID string) (string, error) {
+	dir := filepath.Join(m.jobsDir(), jobID)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, 0o755)
+}
+
+// Remove deletes the job's workspace.
+func (m *Manager) Remove(jobID string) {
+	if err := os.RemoveAll(filepath.Join(m.jobsDir(), jobID)); err != nil {
+		log.Printf("job %s: remove workspace: %v", jobID, err)
+	}
+}
+
+// CheckQuota returns a *QuotaError when dir uses more than QuotaMB.
+func (m *Manager) CheckQuota(dir string) error {
+	if m.QuotaMB <= 0 {
+		return nil
+	}
+	used, err := Usage(dir)
+	if err != nil {
+		return err
+	}
+	if usedMB := used >> 20; usedMB > int64(m.QuotaMB) {
+		return &QuotaError{UsedMB: usedMB, QuotaMB: m.QuotaMB}
+	}
+	return nil
+}
+
+// Acquire returns the cache directory for key, creating it when missing. The
+// caller has it to itself until it calls release; other jobs asking for the
+// same key wait.
+func (m *Manager) Acquire(key string) (dir string, release func(), err error) {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:16])
+
+	m.mu.Lock()
+	if m.entries == nil {
+		m.entries = make(map[string]*entry)
+	}
+	e := m.entries[name]
+	if e == nil {
+		e = &entry{dir: filepath.Join(m.cacheDir(), name)}
+		m.entries[name] = e
+	}
+	e.users++
+	m.mu.Unlock()
+
+	e.lock.Lock()
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		m.done(e)
+		return "", nil, err
+	}
+	return e.dir, func() { m.done(e) }, nil
+}
+
+// done records the entry's new size and last use and evicts what no longer
+// fits.
+func (m *Manager) done(e *entry) {
+	size, _ := Usage(e.dir)
+	now := time.Now()
+	_ = os.Chtimes(e.dir, now, now)
+	e.lock.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.users--
+	e.size = size
+	e.lastUsed = now
+	m.evictLocked()
+}
+
+// evictLocked removes the least recently used cache entries nobody holds
+// until the cache fits in CacheMB.
+func (m *Manager) evictLocked() {
+	var total int64
+	var idle []string
+	for name, e := range m.entries {
+		total += e.size
+		if e.users == 0 {
+			idle = append(idle, name)
+		}
+	}
+	limit := int64(m.CacheMB) << 20
+	sort.Slice(idle, func(i, j int) bool { return m.entries[idle[i]].lastUsed.Before(m.entries[idle[j]].lastUsed) })
+	for _, name := range idle {
+		if total <= limit {
+			return
+		}
+		e := m.entries[name]
+		if err := os.RemoveAll(e.dir); err != nil {
+			log.Printf("workspace cache: evict %s: %v", e.dir, err)
+			continue
+		}
+		delete(m.entries, name)
+		total -= e.size
+	}
+}
+
+// Usage is the disk used by the files under dir.
+func Usage(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}